@@ -0,0 +1,17 @@
+package engine
+
+import (
+	"time"
+)
+
+// Source of time for the search and time management. Tests replace the system clock with a simulated one
+// so that timing of the search does not depend on the speed of the machine.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
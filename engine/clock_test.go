@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// Simulated clock that advances by nodeTime with every node evaluated by the group. Search takes the same
+// (simulated) time on every run whatever the speed of the machine.
+type nodeClock struct {
+	threads  *SearchThreads
+	start    time.Time
+	nodeTime time.Duration
+}
+
+func (clock *nodeClock) Now() time.Time {
+	return clock.start.Add(time.Duration(clock.threads.evaluatedNodes()) * clock.nodeTime)
+}
+
+// Replays 'go' commands that made the engine lose on time (see notes.log)
+func TestGoWithinTimeBudget(t *testing.T) {
+	// few times slower than the real engine so that the test is short
+	const nodeTime = 50 * time.Microsecond
+	// time limits are checked after every move searched. Node checking the time may still have few
+	// quiescence nodes to finish. The clock is read once per timeCheckInterval checks
+	const slack = (100 + timeCheckInterval) * nodeTime
+	var tests = []struct {
+		position   string
+		goCommand  string
+		millisLeft int
+	}{
+		{"startpos moves e2e4 c7c5 g1f3 d8c7 f1c4 e7e6 b1c3 d7d6 d2d3 c7d8 c1e3 g7g6 d1d2 b8c6 e3g5",
+			"wtime 239171 btime 236628 winc 0 binc 0", 236628},
+		{"startpos moves e2e4 c7c5 f1c4 e7e5 d1h5",
+			"wtime 285088 btime 280307 winc 0 binc 0", 280307},
+		{"startpos moves e2e4",
+			"wtime 500 btime 501 winc 500 binc 500 movestogo 2", 501},
+		{"startpos moves e2e4 e7e5",
+			"wtime 500 btime 501 winc 500 binc 500 movestogo 2", 500},
+		{"startpos moves e2e4 e7e5",
+			"wtime 3000 btime 3000 winc 0 binc 0 movestogo 1", 3000},
+		{"startpos moves e2e4 e7e5",
+			"wtime 100 btime 52152 winc 1000 binc 1000", 100},
+	}
+	for _, test := range tests {
+		t.Run(test.position+" "+test.goCommand, func(t *testing.T) {
+			engine := NewEngine(io.Discard)
+			engine.doPosition(test.position)
+			threads := engine.searchThreads
+			clock := &nodeClock{threads: threads, start: time.Unix(0, 0), nodeTime: nodeTime}
+			threads.SetClock(clock)
+			startTime := clock.Now()
+			limits, ok := engine.parseGoLimits(test.goCommand, startTime)
+			if !ok {
+				t.Fatalf("could not parse %v", test.goCommand)
+			}
+			threads.SetPosition(engine.posGen)
+			threads.setLimits(limits)
+			best := threads.search(startTime)
+
+			elapsed := threads.since(startTime)
+			if elapsed > limits.timeManager.maximum+slack {
+				t.Fatalf("search took %v - longer than planned %v", elapsed, limits.timeManager.maximum)
+			}
+			if elapsed >= time.Duration(test.millisLeft)*time.Millisecond {
+				t.Fatalf("search took %v with %vms left on the clock", elapsed, test.millisLeft)
+			}
+			if len(best.bestLine.moves) == 0 {
+				t.Fatalf("no move found")
+			}
+		})
+	}
+}
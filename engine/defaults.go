@@ -1,5 +1,7 @@
 package engine
 
+import "time"
+
 // Maximum number of plies we expect to reach while searching the game tree in any practical scenario.
 // This also means max line length
 const MaxSearchDepth = 40
@@ -8,4 +10,62 @@ const MaxSearchDepth = 40
 const ExpectedFullMovesToBePlayed = 30
 
 // Typically game adjudicated as draws after 300 moves. So 300 + margin for search depth.
-const killerMovesMaxPly = 350
\ No newline at end of file
+const killerMovesMaxPly = 350
+
+// History of quiet moves is halved once any of its entries exceeds that
+const historyMax int32 = 1 << 14
+
+// Null move pruning is not tried with less plies left. With smaller depths the reduced search would
+// go straight to quiescence.
+const nullMoveMinDepth = 3
+
+// Half width of the first aspiration window around the score from previous iteration. It's doubled on every
+// re-search. Once it's above the max the search falls back to the full window.
+const (
+	aspirationWindowDelta    = 25
+	aspirationWindowMaxDelta = 4 * MaterialPawnScore
+)
+
+// Late move reductions are not applied with less plies left
+const lmrMinDepth = 3
+
+// Late move reduction = lmrBase + ln(remainingDepth) * ln(moveIdx) / lmrDivisor
+const (
+	lmrBase    = 0.75
+	lmrDivisor = 2.25
+)
+
+// Search reads the clock once per that many checks of its limits - well below a millisecond of search.
+// Reading it at every node is costly
+const timeCheckInterval = 256
+
+// 'info currmove' is reported for root moves started after that much time of search
+const currMoveInfoMinTime = 3 * time.Second
+
+// Win/draw/loss model - see winDrawLoss(). Chance of winning is a logistic function of the score: it's 50%
+// with wdlMidpointScore advantage and wdlScale is the spread of the curve. Draws take what's left after
+// wins and losses. Both are picked by hand - not fitted to game results - so that an extra pawn wins about
+// one game in five and an extra piece about four in five.
+const (
+	wdlMidpointScore = 2 * MaterialPawnScore
+	wdlScale         = 0.8 * MaterialPawnScore
+)
+
+// Time management - see timeManager.go
+const (
+	// hard limit is that many times the optimum time for the move...
+	timeHardLimitFactor = 3
+	// ...but no more than that percent of the time left on the clock (unless it's the last move before time control)
+	timeHardLimitClockPercent = 50
+	// soft limit (percent of the optimum time) before the first iteration...
+	timeSoftLimitPercent = 60
+	// ...and its bounds
+	timeSoftLimitMinPercent = 30
+	timeSoftLimitMaxPercent = 200
+	// soft limit changes after every iteration
+	timeBestMoveChangePercent = 40
+	timeStableBestMovePercent = 5
+	timeScoreDropPercent      = 40
+	// score lower than in the previous iteration by more than that is considered a drop
+	timeScoreDropMargin = 30
+)
@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// Single instance of the engine. It owns everything that a game needs: the position, searches, transposition
+// table and options. Engines are independent of each other so many of them can run in one process
+// (e.g. engine vs engine testing). Commands are given in UCI protocol with ParseInputLine() - or in XBoard
+// protocol once 'xboard' command is given. Search started with 'go' runs on its own goroutine so that
+// commands can be read while searching.
+type Engine struct {
+	posGen             *Generator
+	searchThreads      *SearchThreads
+	transpositionTable *TranspositionTable
+	options            options
+	// responses to the commands are written to it. Safe to write from the search goroutine and the command one
+	out io.Writer
+	// receive events of every search. The first one is the front-end of the protocol: uci or xboard's listener
+	listeners []SearchListener
+	// prints events of the search as UCI info and bestmove to out
+	uci *uciListener
+	// game played in XBoard protocol. nil until 'xboard' command switches the engine to it
+	xboard *xboardState
+	// set by 'quit' command
+	quit bool
+	// set by 'debug on'. Diagnostics are printed as 'info string' then
+	debug bool
+	// when set, CPU profile of the search is written to it
+	profileFile *os.File
+	// done when search started with 'go' publishes its best move
+	searching sync.WaitGroup
+	// held while a command is executed - on the goroutine calling ParseInputLine() or on the search goroutine
+	// running the commands queued while searching. Guards the fields below and everything above but out.
+	commandMutex sync.Mutex
+	// from 'go' until the search publishes its best move
+	searchRunning bool
+	// commands that would interfere with the running search. Executed once it's done
+	pendingCommands []string
+}
+
+// Writer that many goroutines can write to. Lines written with a single Fprintln() do not interleave.
+type syncWriter struct {
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+func (writer *syncWriter) Write(p []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	return writer.out.Write(p)
+}
+
+// Returns new engine that writes its responses to out.
+func NewEngine(out io.Writer) *Engine {
+	syncOut := &syncWriter{out: out}
+	engine := &Engine{
+		transpositionTable: NewTranspositionTable(hashSizeDefault),
+		options:            defaultOptions(),
+		out:                syncOut,
+		uci:                &uciListener{out: syncOut},
+	}
+	engine.listeners = []SearchListener{engine.uci}
+	engine.searchThreads = engine.newSearchThreads()
+	return engine
+}
+
+// Returns true once 'quit' command was given.
+func (engine *Engine) HasQuit() bool {
+	return engine.quit
+}
+
+// CPU profile of every search will be written to profileFile.
+func (engine *Engine) SetProfileFile(profileFile *os.File) {
+	engine.profileFile = profileFile
+}
+
+// Adds listener of the events of every next search. Must not be called while the engine searches.
+func (engine *Engine) AddSearchListener(listener SearchListener) {
+	engine.listeners = append(engine.listeners, listener)
+}
+
+// Blocks until the search started with 'go' command (and the ones queued while it was running) publishes
+// its best move.
+func (engine *Engine) Wait() {
+	engine.searching.Wait()
+}
+
+func (engine *Engine) newSearchThreads() *SearchThreads {
+	return NewSearchThreads(engine.options.threadsCount, engine.transpositionTable)
+}
+
+// Runs the search prepared in threads, publishes its best move and returns it. Profiles the search if
+// profile file is set.
+func (engine *Engine) runSearch(threads *SearchThreads, startTime time.Time) *Search {
+	if engine.profileFile != nil {
+		fmt.Fprintln(engine.out, "starting profiling")
+		pprof.StartCPUProfile(engine.profileFile)
+		defer engine.stopProfiling()
+	}
+	best := threads.search(startTime)
+	threads.publishBestMove(best, startTime)
+	return best
+}
+
+// Starts the search of the engine's position on its own goroutine. Once the search publishes its best move,
+// bestMoveFound (if not nil) is called with it and then the commands queued meanwhile are executed - both
+// with commandMutex held. Must be called with commandMutex held.
+func (engine *Engine) startSearch(limits searchLimits, bestMoveFound func(best *Search)) {
+	threads := engine.searchThreads
+	threads.SetPosition(engine.posGen)
+	threads.setOptions(engine.options)
+	threads.setListeners(engine.listeners)
+	threads.setLimits(limits)
+	engine.searchRunning = true
+	engine.searching.Add(1)
+	go func() {
+		defer engine.searching.Done()
+		best := engine.runSearch(threads, limits.startTime)
+
+		engine.commandMutex.Lock()
+		defer engine.commandMutex.Unlock()
+		engine.searchRunning = false
+		if bestMoveFound != nil {
+			bestMoveFound(best)
+		}
+		engine.executePendingCommands()
+	}()
+}
+
+func (engine *Engine) stopProfiling() {
+	fmt.Fprintln(engine.out, "iterative deepening: stopping profiling.....")
+	pprof.StopCPUProfile()
+}
+
+// Limits of Engine.Search(). Zero values mean no limit - search without any limits runs until ctx is done.
+type SearchLimits struct {
+	Depth    int
+	Nodes    int64
+	MoveTime time.Duration
+	// stop once mate in that many moves (or less) is found
+	Mate int
+	// moves in UCI notation to choose from. Empty for all legal moves
+	SearchMoves []string
+}
+
+// Result of Engine.Search(). Moves are in UCI notation.
+type SearchResult struct {
+	BestMove string
+	// in centipawns, positive when the side to move is better
+	Score int
+	// moves to mate when the search found one - negative when the side to move is getting mated. 0 otherwise
+	MateIn int
+	// deepest iteration completed
+	Depth int
+	// principal variation - starts with BestMove
+	PV    []string
+	Nodes int64
+	Time  time.Duration
+}
+
+var ErrNoLegalMoves = errors.New("no legal moves in the position")
+
+// Searches for the best move in the current position of the game. Search stops when any of the limits is
+// reached or ctx is done (whatever comes first). Uses the options (e.g. Threads, Hash) set for the engine and
+// publishes events to its listeners. Must not be called while the engine searches (also one started with 'go').
+func (engine *Engine) Search(ctx context.Context, game *Game, limits SearchLimits) (SearchResult, error) {
+	if len(game.gen.GenerateMoves()) == 0 {
+		return SearchResult{}, ErrNoLegalMoves
+	}
+	threads := engine.searchThreads
+	startTime := threads.clock.Now()
+	moveTime := time.Duration(infiniteMillis) * time.Millisecond
+	if limits.MoveTime > 0 {
+		moveTime = limits.MoveTime
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Sub(startTime) < moveTime {
+		moveTime = deadline.Sub(startTime)
+	}
+	searchLimits := newSearchLimits(startTime.Add(moveTime))
+	searchLimits.startTime = startTime
+	searchLimits.timeManager = newFixedTimeManager(moveTime)
+	if limits.Depth > 0 {
+		searchLimits.maxDepth = limits.Depth
+	}
+	searchLimits.maxNodes = limits.Nodes
+	searchLimits.mateIn = limits.Mate
+	for _, uciMove := range limits.SearchMoves {
+		move, err := game.legalMove(uciMove)
+		if err != nil {
+			return SearchResult{}, fmt.Errorf("invalid search move: %w", err)
+		}
+		searchLimits.searchMoves = append(searchLimits.searchMoves, move)
+	}
+
+	threads.SetPosition(game.gen)
+	threads.setOptions(engine.options)
+	threads.setListeners(engine.listeners)
+	threads.setLimits(searchLimits)
+	searchDone := make(chan struct{})
+	defer close(searchDone)
+	go func() {
+		select {
+		case <-ctx.Done():
+			threads.Stop()
+		case <-searchDone:
+		}
+	}()
+	best := engine.runSearch(threads, startTime)
+	if len(best.bestLine.moves) == 0 {
+		return SearchResult{}, ErrNoLegalMoves
+	}
+
+	result := SearchResult{
+		BestMove: best.bestLine.moves[0].String(),
+		Score:    best.bestScore,
+		Depth:    best.depthCompleted,
+		Nodes:    threads.evaluatedNodes(),
+		Time:     threads.since(startTime),
+	}
+	if closeToMate(best.bestScore) {
+		result.MateIn = fullMovesToMate(best.bestScore)
+	}
+	for _, move := range best.bestLine.moves {
+		result.PV = append(result.PV, move.String())
+	}
+	return result, nil
+}
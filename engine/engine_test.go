@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Returns the move from the last 'bestmove' line of the engine's output.
+func lastBestMove(out string) string {
+	idx := strings.LastIndex(out, "bestmove ")
+	if idx < 0 {
+		return ""
+	}
+	return strings.Fields(out[idx:])[1]
+}
+
+func TestIndependentEngines(t *testing.T) {
+	var whiteOut, blackOut bytes.Buffer
+	white, black := NewEngine(&whiteOut), NewEngine(&blackOut)
+
+	// mate in 1 for each engine's side - only one of them would find it if engines shared the position
+	white.ParseInputLine("position fen 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	black.ParseInputLine("position fen r5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1")
+	black.ParseInputLine("setoption name MultiPV value 2")
+	white.ParseInputLine("go depth 4")
+	black.ParseInputLine("go depth 4")
+	white.Wait()
+	black.Wait()
+
+	if move := lastBestMove(whiteOut.String()); move != "a1a8" {
+		t.Errorf("expected white engine to play a1a8 but was %q in output:\n%v", move, whiteOut.String())
+	}
+	if move := lastBestMove(blackOut.String()); move != "a8a1" {
+		t.Errorf("expected black engine to play a8a1 but was %q in output:\n%v", move, blackOut.String())
+	}
+	if strings.Contains(whiteOut.String(), "multipv 2") {
+		t.Errorf("MultiPV set for one engine affected the other:\n%v", whiteOut.String())
+	}
+	white.ParseInputLine("quit")
+	if !white.HasQuit() || black.HasQuit() {
+		t.Errorf("quit should stop only one engine")
+	}
+}
+
+func TestEngineSearch(t *testing.T) {
+	engine := NewEngine(io.Discard)
+	game, _ := NewGameFromFen("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	result, err := engine.Search(context.Background(), game, SearchLimits{Depth: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove != "a1a8" || result.MateIn != 1 || result.PV[0] != result.BestMove || result.Nodes == 0 {
+		t.Fatalf("expected mate in 1 with a1a8 but was %+v", result)
+	}
+
+	game = NewGame()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	result, err = engine.Search(ctx, game, SearchLimits{SearchMoves: []string{"g1f3", "b1c3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove != "g1f3" && result.BestMove != "b1c3" {
+		t.Fatalf("expected one of search moves but was %+v", result)
+	}
+	if result.Time > time.Second {
+		t.Fatalf("search did not stop when the context was cancelled: %v", result.Time)
+	}
+
+	if _, err = engine.Search(ctx, game, SearchLimits{SearchMoves: []string{"e2e5"}}); err == nil {
+		t.Fatalf("expected error for illegal search move")
+	}
+	game.Move("f2f3")
+	game.Move("e7e5")
+	game.Move("g2g4")
+	game.Move("d8h4")
+	if _, err = engine.Search(context.Background(), game, SearchLimits{Depth: 1}); err != ErrNoLegalMoves {
+		t.Fatalf("expected %v but was %v", ErrNoLegalMoves, err)
+	}
+}
+
+// Listener that keeps all the events of the search
+type recordingListener struct {
+	events []SearchEvent
+}
+
+func (listener *recordingListener) OnSearchEvent(event SearchEvent) {
+	listener.events = append(listener.events, event)
+}
+
+func TestSearchEvents(t *testing.T) {
+	var out bytes.Buffer
+	engine := NewEngine(&out)
+	recorder := &recordingListener{}
+	engine.AddSearchListener(recorder)
+	engine.ParseInputLine("setoption name MultiPV value 2")
+	result, err := engine.Search(context.Background(), NewGame(), SearchLimits{Depth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iterationsAtDepth := map[int]int{}
+	for i, event := range recorder.events {
+		switch event := event.(type) {
+		case IterationEvent:
+			iterationsAtDepth[event.Depth]++
+			if event.MultiPv != iterationsAtDepth[event.Depth] || len(event.PV) == 0 {
+				t.Errorf("unexpected iteration event %+v", event)
+			}
+		case BestMoveEvent:
+			if i != len(recorder.events)-1 {
+				t.Errorf("best move should be the last event but was %v of %v", i, len(recorder.events))
+			}
+			if event.Move.String() != result.BestMove || event.Depth != result.Depth || event.PV[0] != event.Move {
+				t.Errorf("best move event %+v does not match the result %+v", event, result)
+			}
+		}
+	}
+	for depth := 2; depth <= result.Depth; depth++ {
+		if iterationsAtDepth[depth] != 2 {
+			t.Errorf("expected 2 lines at depth %v but got %v", depth, iterationsAtDepth[depth])
+		}
+	}
+	if _, ok := recorder.events[len(recorder.events)-1].(BestMoveEvent); !ok {
+		t.Errorf("no best move event at the end of %v events", len(recorder.events))
+	}
+	// UCI front-end is just another listener
+	if move := lastBestMove(out.String()); move != result.BestMove {
+		t.Errorf("expected bestmove %v in output:\n%v", result.BestMove, out.String())
+	}
+}
@@ -97,8 +97,12 @@ func NewPositionFromFen(fen string) (Position, error) {
 		pos.enPassSquare = square(file) + square(rank)
 	}
 
-	//TODO read rest of the fields
-	// halfmoveClockStr := fields[4]
+	halfmoveClockStr := fields[4]
+	halfmoveClock, err := strconv.Atoi(halfmoveClockStr)
+	if err != nil || halfmoveClock < 0 {
+		return Position{}, fmt.Errorf("invalid halfmove clock: %v", halfmoveClockStr)
+	}
+	pos.halfmoveClock = int16(halfmoveClock)
 
 	fullMoveCounterStr := fields[5]
 	fullMoveCounter, err := strconv.Atoi(fullMoveCounterStr)
@@ -113,11 +117,70 @@ func NewPositionFromFen(fen string) (Position, error) {
 	if pos.flags & FlagWhiteTurn == 0 {
 		pos.ply++
 	}
-	
+	pos.hash = pos.computeHash()
 
 	return pos, nil
 }
 
+// Returns FEN string describing pos. Inverse of NewPositionFromFen()
+func (pos *Position) Fen() string {
+	var sb strings.Builder
+	for r := Rank8; r >= Rank1; r -= UnitRank {
+		emptySquares := 0
+		for f := A; f <= H; f++ {
+			p := pos.GetAtFileRank(f, r)
+			if p == NullPiece {
+				emptySquares++
+				continue
+			}
+			if emptySquares > 0 {
+				sb.WriteString(strconv.Itoa(emptySquares))
+				emptySquares = 0
+			}
+			sb.WriteRune(pieceToChar(p))
+		}
+		if emptySquares > 0 {
+			sb.WriteString(strconv.Itoa(emptySquares))
+		}
+		if r != Rank1 {
+			sb.WriteRune('/')
+		}
+	}
+
+	if pos.flags&FlagWhiteTurn != 0 {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	if pos.flags&castlingFlagsMask == 0 {
+		sb.WriteRune('-')
+	} else {
+		if pos.flags&FlagWhiteCanCastleKside != 0 {
+			sb.WriteRune('K')
+		}
+		if pos.flags&FlagWhiteCanCastleQside != 0 {
+			sb.WriteRune('Q')
+		}
+		if pos.flags&FlagBlackCanCastleKside != 0 {
+			sb.WriteRune('k')
+		}
+		if pos.flags&FlagBlackCanCastleQside != 0 {
+			sb.WriteRune('q')
+		}
+	}
+
+	if pos.enPassSquare == InvalidSquare {
+		sb.WriteString(" - ")
+	} else {
+		sb.WriteString(" " + pos.enPassSquare.String() + " ")
+	}
+
+	fullMoveCounter := pos.ply/2 + 1
+	sb.WriteString(fmt.Sprintf("%d %d", pos.halfmoveClock, fullMoveCounter))
+	return sb.String()
+}
+
 func isASCII(s string) bool {
 	for i := 0; i < len(s); i++ {
 		if s[i] > unicode.MaxASCII {
@@ -158,3 +221,34 @@ func charToPiece(c rune) piece {
 		return NullPiece
 	}
 }
+
+func pieceToChar(p piece) rune {
+	switch p {
+	case BPawn:
+		return 'p'
+	case BKnight:
+		return 'n'
+	case BBishop:
+		return 'b'
+	case BRook:
+		return 'r'
+	case BQueen:
+		return 'q'
+	case BKing:
+		return 'k'
+
+	case WPawn:
+		return 'P'
+	case WKnight:
+		return 'N'
+	case WBishop:
+		return 'B'
+	case WRook:
+		return 'R'
+	case WQueen:
+		return 'Q'
+	case WKing:
+		return 'K'
+	}
+	panic(fmt.Sprintf("Unknown piece %X", byte(p)))
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFenRoundTrip(t *testing.T) {
+	forEachPerftPosition(t, func(t *testing.T, fenStr string, gen *Generator) {
+		if exported := gen.getTopPos().Fen(); exported != fenStr {
+			t.Fatalf("expected %v but was %v", fenStr, exported)
+		}
+	})
+}
+
+func TestFenRoundTripAfterMoves(t *testing.T) {
+	var tests = []struct {
+		uciMoves    []string
+		expectedFen string
+	}{
+		{[]string{}, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{[]string{"e2e4"}, "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{[]string{"e2e4", "c7c5", "g1f3"}, "rnbqkbnr/pp1ppppp/8/2p5/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"},
+		{[]string{"g1f3", "g8f6", "h1g1", "h8g8"}, "rnbqkbr1/pppppppp/5n2/8/8/5N2/PPPPPPPP/RNBQKBR1 w Qq - 4 3"},
+		{[]string{"e2e4", "d7d5", "e4d5", "d8d5", "b1c3", "d5a5", "d2d4", "c7c6", "g1f3", "c8g4", "c1d2", "b8d7",
+			"f1e2", "e7e6", "e1g1", "e8c8"},
+			"2kr1bnr/pp1n1ppp/2p1p3/q7/3P2b1/2N2N2/PPPBBPPP/R2Q1RK1 w - - 2 9"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("moves:%v", test.uciMoves), func(t *testing.T) {
+			gen := NewGenerator()
+			for _, uciMove := range test.uciMoves {
+				move, err := parseMoveString(uciMove)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gen.ApplyUciMove(move)
+			}
+			exported := gen.getTopPos().Fen()
+			if exported != test.expectedFen {
+				t.Fatalf("expected %v but was %v", test.expectedFen, exported)
+			}
+			parsed, err := NewPositionFromFen(exported)
+			if err != nil {
+				t.Fatalf("Could not parse exported FEN: %v due to: %v", exported, err)
+			}
+			if parsed.hash != gen.getTopPos().hash {
+				t.Fatalf("hash of position parsed from %v differs from the original", exported)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Chess game for Go programs that embed the engine: the current position together with the moves that led
+// to it (needed to detect repetitions). Moves are given and returned as strings in UCI (e.g. e2e4, e7e8q) or
+// SAN (e.g. e4, exd5, O-O, e8=Q+) notation. Search it with Engine.Search().
+type Game struct {
+	gen *Generator
+}
+
+// Status of the game in its current position
+type GameStatus int
+
+const (
+	Ongoing GameStatus = iota
+	Checkmate
+	Stalemate
+	DrawByFiftyMoveRule
+	DrawByThreefoldRepetition
+)
+
+func (status GameStatus) String() string {
+	switch status {
+	case Ongoing:
+		return "ongoing"
+	case Checkmate:
+		return "checkmate"
+	case Stalemate:
+		return "stalemate"
+	case DrawByFiftyMoveRule:
+		return "draw by fifty-move rule"
+	case DrawByThreefoldRepetition:
+		return "draw by threefold repetition"
+	}
+	return fmt.Sprintf("GameStatus(%d)", int(status))
+}
+
+// Returns game in the starting position.
+func NewGame() *Game {
+	return &Game{gen: NewGenerator()}
+}
+
+// Returns game in the position given in Forsyth-Edwards Notation.
+func NewGameFromFen(fen string) (*Game, error) {
+	gen, err := NewGeneratorFromFen(fen)
+	if err != nil {
+		return nil, err
+	}
+	return &Game{gen: gen}, nil
+}
+
+// Returns the current position in Forsyth-Edwards Notation.
+func (game *Game) Fen() string {
+	return game.gen.getTopPos().Fen()
+}
+
+// Returns true when white is to move.
+func (game *Game) WhiteToMove() bool {
+	return game.gen.getTopPos().flags&FlagWhiteTurn != 0
+}
+
+// Returns legal moves in UCI notation.
+func (game *Game) LegalMoves() []string {
+	var moves []string
+	for _, move := range game.gen.GenerateMoves() {
+		moves = append(moves, move.mov.String())
+	}
+	return moves
+}
+
+// Returns legal moves in SAN notation - in the same order as LegalMoves().
+func (game *Game) LegalMovesSan() []string {
+	var moves []string
+	for _, move := range slices.Clone(game.gen.GenerateMoves()) {
+		moves = append(moves, game.gen.toSan(move.mov))
+	}
+	return moves
+}
+
+// Plays move given in UCI notation. Returns error if the move is not legal.
+func (game *Game) Move(uciMove string) error {
+	move, err := game.legalMove(uciMove)
+	if err != nil {
+		return err
+	}
+	game.gen.ApplyUciMove(move)
+	return nil
+}
+
+// Plays move given in SAN notation. Check, mate and annotation symbols are optional. Returns error if
+// the move is not legal.
+func (game *Game) MoveSan(sanMove string) error {
+	wanted := strings.TrimRight(sanMove, "+#!?")
+	for _, move := range slices.Clone(game.gen.GenerateMoves()) {
+		if strings.TrimRight(game.gen.toSan(move.mov), "+#") == wanted {
+			game.gen.ApplyUciMove(move.mov)
+			return nil
+		}
+	}
+	return fmt.Errorf("illegal move: %v", sanMove)
+}
+
+// Converts move in UCI notation to SAN. Returns error if the move is not legal.
+func (game *Game) ToSan(uciMove string) (string, error) {
+	move, err := game.legalMove(uciMove)
+	if err != nil {
+		return "", err
+	}
+	return game.gen.toSan(move), nil
+}
+
+// Returns true when the side to move is in check.
+func (game *Game) InCheck() bool {
+	return game.gen.getTopPos().isCurrentKingUnderCheck()
+}
+
+func (game *Game) Status() GameStatus {
+	pos := game.gen.getTopPos()
+	if len(game.gen.GenerateMoves()) == 0 {
+		if pos.isCurrentKingUnderCheck() {
+			return Checkmate
+		}
+		return Stalemate
+	}
+	if isFiftyMoveRuleDraw(pos) {
+		return DrawByFiftyMoveRule
+	}
+	// game is always at the root - only repetitions from the history count
+	if game.gen.isRepetition() {
+		return DrawByThreefoldRepetition
+	}
+	return Ongoing
+}
+
+// Returns static evaluation of the current position in centipawns. Positive when the side to move is better.
+func (game *Game) Evaluate() int {
+	return Evaluate(game.gen.getTopPos(), 0)
+}
+
+// Returns legal move given in UCI notation.
+func (game *Game) legalMove(uciMove string) (Move, error) {
+	parsed, err := parseMoveString(uciMove)
+	if err != nil {
+		return Move{}, err
+	}
+	move, ok := game.gen.findLegalMove(parsed)
+	if !ok {
+		return Move{}, fmt.Errorf("illegal move: %v", uciMove)
+	}
+	return move, nil
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestToSan(t *testing.T) {
+	var tests = []struct {
+		fen         string
+		uciMove     string
+		expectedSan string
+	}{
+		{startingFen, "e2e4", "e4"},
+		{startingFen, "g1f3", "Nf3"},
+		{"4k3/8/8/3p4/8/8/8/3RK3 w - - 0 1", "d1d5", "Rxd5"},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "b1d2", "Nbd2"},
+		{"4k3/8/8/R7/8/8/8/R3K3 w - - 0 1", "a1a3", "R1a3"},
+		{"4k3/8/8/8/8/Q7/8/Q1Q1K3 w - - 0 1", "a1b2", "Qa1b2"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8n", "b8=N"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "O-O-O"},
+		{"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+	}
+	for _, test := range tests {
+		t.Run(test.fen+" "+test.uciMove, func(t *testing.T) {
+			game, err := NewGameFromFen(test.fen)
+			if err != nil {
+				t.Fatalf("Could not parse FEN: %v due to: %v", test.fen, err)
+			}
+			san, err := game.ToSan(test.uciMove)
+			if err != nil || san != test.expectedSan {
+				t.Fatalf("expected %v but was %v (error: %v)", test.expectedSan, san, err)
+			}
+			if !slices.Contains(game.LegalMovesSan(), test.expectedSan) {
+				t.Fatalf("%v not listed in legal moves %v", test.expectedSan, game.LegalMovesSan())
+			}
+			if err = game.MoveSan(test.expectedSan); err != nil {
+				t.Fatal(err)
+			}
+			expected, _ := NewGameFromFen(test.fen)
+			expected.Move(test.uciMove)
+			if game.Fen() != expected.Fen() {
+				t.Fatalf("expected %v after %v but was %v", expected.Fen(), test.expectedSan, game.Fen())
+			}
+		})
+	}
+}
+
+func TestGameStatus(t *testing.T) {
+	var tests = []struct {
+		fen            string
+		moves          []string
+		expectedStatus GameStatus
+	}{
+		{startingFen, nil, Ongoing},
+		{startingFen, []string{"f2f3", "e7e5", "g2g4", "d8h4"}, Checkmate},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", nil, Stalemate},
+		{"4k3/8/8/8/8/8/8/R3K3 w - - 99 80", []string{"a1a2"}, DrawByFiftyMoveRule},
+		{startingFen, []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1"}, Ongoing},
+		{startingFen, []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"}, DrawByThreefoldRepetition},
+	}
+	for _, test := range tests {
+		t.Run(test.fen, func(t *testing.T) {
+			game, err := NewGameFromFen(test.fen)
+			if err != nil {
+				t.Fatalf("Could not parse FEN: %v due to: %v", test.fen, err)
+			}
+			for _, move := range test.moves {
+				if err = game.Move(move); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if status := game.Status(); status != test.expectedStatus {
+				t.Fatalf("expected %v but was %v", test.expectedStatus, status)
+			}
+		})
+	}
+}
+
+func TestGameIllegalMove(t *testing.T) {
+	game := NewGame()
+	for _, move := range []string{"e2e5", "e1e2", "a7a6", "xyz"} {
+		if err := game.Move(move); err == nil {
+			t.Errorf("expected error for illegal move %v", move)
+		}
+	}
+	if err := game.MoveSan("Ke2"); err == nil {
+		t.Errorf("expected error for illegal move Ke2")
+	}
+	if game.Fen() != startingFen {
+		t.Errorf("illegal moves changed the position to %v", game.Fen())
+	}
+	if len(game.LegalMoves()) != 20 || game.InCheck() || !game.WhiteToMove() {
+		t.Errorf("unexpected starting position state: %v", game.LegalMoves())
+	}
+}
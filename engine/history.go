@@ -0,0 +1,63 @@
+package engine
+
+// History heuristic -> https://www.chessprogramming.org/History_Heuristic
+// Quiet moves that caused beta cutoffs are rewarded with remainingDepth^2 (cutoffs close to the root
+// save more work). Quiet moves without killer bonus are ordered by their history.
+//
+// Countermove heuristic -> https://www.chessprogramming.org/Countermove_Heuristic
+// Quiet move that caused beta cutoff is remembered as a refutation of the opponent's move that
+// preceded it.
+
+// Adds reward for quiet move that caused beta cutoff after prevMove.
+func (search *Search) updateHistory(pos *Position, remainingDepth int, move, prevMove Move) {
+	entry := &search.history[pos.flags&FlagWhiteTurn][move.from][move.to]
+	*entry += int32(remainingDepth * remainingDepth)
+	if *entry > historyMax {
+		search.ageHistory()
+	}
+	if prevMove != (Move{}) {
+		search.counterMoves[prevMove.from][prevMove.to] = move
+	}
+}
+
+// Ranks quiet moves by history. Refutation of prevMove goes first - right after killers
+// (see applyKillerMoveBonus()). Rankings stay below rankingBonusKiller2nd.
+func (search *Search) applyHistoryRanking(moves []rankedMove, pos *Position, prevMove Move) {
+	history := &search.history[pos.flags&FlagWhiteTurn]
+	counterMove := search.counterMoves[prevMove.from][prevMove.to]
+	for i, m := range moves {
+		if m.flags&mFlagTactical != 0 {
+			continue
+		}
+		if prevMove != (Move{}) && m.mov == counterMove {
+			moves[i].ranking = rankingBonusCounterMove
+		} else {
+			// int so that multiplication does not overflow
+			moves[i].ranking = int16(int(history[m.mov.from][m.mov.to]) * int(rankingHistoryMax) / int(historyMax))
+		}
+	}
+}
+
+// Halves the history so that new cutoffs matter more than the ones from previous searches.
+func (search *Search) ageHistory() {
+	for color := range search.history {
+		for from := range search.history[color] {
+			for to := range search.history[color][from] {
+				search.history[color][from][to] /= 2
+			}
+		}
+	}
+}
+
+func (search *Search) clearHistory() {
+	clear(search.history[:])
+	clear(search.counterMoves[:])
+}
+
+// Returns move that led to the position at depth. Move{} at the root or after the null move.
+func (search *Search) previousMove(depth int) Move {
+	if depth == 0 {
+		return Move{}
+	}
+	return search.lineMoves[depth-1]
+}
@@ -2,6 +2,8 @@ package engine
 
 import (
 	"fmt"
+	"io"
+	"slices"
 )
 
 type Move struct {
@@ -21,16 +23,24 @@ type rankedMove struct {
 const (
 	// set for move that changes material (is capture or promotion)
 	mFlagTactical byte = 1 << iota
+	// set for capture that loses material according to static exchange evaluation (see.go)
+	mFlagLosingCapture
 )
 
 // ranking bonud for move that is on line returned by previous iteration of iterative deepening
 // TODO there's some overlap between some sacrificing captures and killer moves.. run some games to fine-tune it
 // Watch out for overlflow - rankedMove.rakning is int16!
 const (
+	rankingBonusHashMove  int16 = 25000
 	rankingBonusPvMove    int16 = 10000
 	rankingBonusTactical  int16 = 9000
 	rankingBonusKiller1st int16 = 8000
 	rankingBonusKiller2nd       = 7000
+	rankingBonusCounterMove     = 6000
+	// quiet moves are ranked by history from 0 up to that
+	rankingHistoryMax int16 = 5000
+	// ranking of a capture that loses material (before see() result is added). Below quiet moves
+	rankingLosingCapture int16 = -20000
 )
 
 type Generator struct {
@@ -42,6 +52,8 @@ type Generator struct {
 	plyIdx int16
 	//index of the first move in currently searched line (so it can be print in quiescence search)
 	firstMoveIdx int
+	// hashes of positions played in the game before posStack[0] - oldest first. Needed to detect repetitions
+	history []uint64
 }
 
 const (
@@ -105,6 +117,19 @@ func newMoveStack() [][]rankedMove {
 	return newMoveStack
 }
 
+// Returns independent copy of positions and game history - so that gen and the copy can be searched
+// at the same time. Moves generated so far are not copied.
+func (gen *Generator) clone() *Generator {
+	newPosStack := make([]Position, plyBufferCapacity)
+	copy(newPosStack, gen.posStack[:gen.plyIdx+1])
+	return &Generator{
+		posStack: newPosStack,
+		movStack: newMoveStack(),
+		plyIdx:   gen.plyIdx,
+		history:  slices.Clone(gen.history),
+	}
+}
+
 // Pushes legalMove on top of posStack. Panics if the move is illegal
 func (gen *Generator) PushMove(legalMove Move) {
 	gen.posStack[gen.plyIdx+1] = gen.posStack[gen.plyIdx]
@@ -116,18 +141,71 @@ func (gen *Generator) PushMove(legalMove Move) {
 	}
 }
 
+// Pushes position with the side to move flipped on top of posStack - see Position.MakeNullMove()
+func (gen *Generator) PushNullMove() {
+	gen.posStack[gen.plyIdx+1] = gen.posStack[gen.plyIdx]
+	gen.plyIdx++
+	gen.posStack[gen.plyIdx].MakeNullMove()
+}
+
 func (gen *Generator) ApplyUciMove(moveFromUci Move) {
 	if gen.getTopPos().board[moveFromUci.from]&ColorlessPiece == Pawn &&
 		(moveFromUci.from.getRank() == Rank7 && moveFromUci.to.getRank() == Rank5 ||
 			moveFromUci.from.getRank() == Rank2 && moveFromUci.to.getRank() == Rank4) {
 		moveFromUci.enPassant = (moveFromUci.from + moveFromUci.to) / 2
 	}
+	gen.history = append(gen.history, gen.getTopPos().hash)
 	success := gen.posStack[gen.plyIdx].MakeMove(moveFromUci)
 	if !success {
 		panic(fmt.Sprintf("Applying uci move %v resulted in illegal position %v", moveFromUci, gen.getTopPos()))
 	}
 }
 
+// Returns legal move in the position on top of the stack that has the same squares and promotion
+// as parsedMove (see parseMoveString()). False if there's none.
+func (gen *Generator) findLegalMove(parsedMove Move) (Move, bool) {
+	for _, legal := range gen.GenerateMoves() {
+		if legal.mov.from == parsedMove.from && legal.mov.to == parsedMove.to && legal.mov.promoteTo == parsedMove.promoteTo {
+			return legal.mov, true
+		}
+	}
+	return Move{}, false
+}
+
+// Returns true if position on top of the stack should be scored as a draw by repetition.
+// Position repeated once inside the searched line (after posStack[0]) is treated as a draw. There's no
+// point in searching it again - side that could improve on it would have done so the first time.
+// Positions from the game history (including posStack[0]) must be repeated twice - actual threefold repetition.
+func (gen *Generator) isRepetition() bool {
+	top := gen.getTopPos()
+	hash := top.hash
+	repetitionsBeforeRoot := 0
+	// positions before the last capture or pawn move can't be repeated
+	oldestPly := max(int(gen.plyIdx)-int(top.halfmoveClock), -len(gen.history))
+	// only positions with the same side to move can be repeated
+	for ply := int(gen.plyIdx) - 2; ply >= oldestPly; ply -= 2 {
+		if ply > 0 {
+			if gen.posStack[ply].hash == hash {
+				return true
+			}
+			continue
+		}
+		var historicHash uint64
+		if ply == 0 {
+			historicHash = gen.posStack[0].hash
+		} else {
+			historicHash = gen.history[len(gen.history)+ply]
+		}
+		if historicHash == hash {
+			repetitionsBeforeRoot++
+			if repetitionsBeforeRoot == 2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (gen Generator) getTopPos() *Position {
 	return &gen.posStack[gen.plyIdx]
 }
@@ -158,6 +236,9 @@ func (gen *Generator) generateLegalMoves(generateSthPseudolegal func()) []ranked
 		success := isLegal(gen.getTopPos(), pseudoMove.mov)
 		// move is valid
 		if success {
+			if pseudoMove.flags&mFlagTactical != 0 {
+				gen.getTopPos().rankCaptureBySee(&pseudoMove)
+			}
 			(*rankedMoves)[i] = pseudoMove
 			i++
 		}
@@ -166,13 +247,12 @@ func (gen *Generator) generateLegalMoves(generateSthPseudolegal func()) []ranked
 	return *rankedMoves
 }
 
-var tested_for_legality Position
-
 // Returns true if pseudolegal is legal in pos. False otherwise.
 func isLegal(pos *Position, pseudolegal Move) bool {
-	// it's important to assign this to global var, otherwise a lot of GC kicks in for some reason
-	tested_for_legality = *pos
-	return (&tested_for_legality).MakeMove(pseudolegal)
+	// Copy stays on the stack as long as MakeMove() does not let its receiver escape (check with -gcflags=-m).
+	// Otherwise a lot of GC kicks in.
+	testedForLegality := *pos
+	return testedForLegality.MakeMove(pseudolegal)
 }
 
 func (gen *Generator) Perft(depth int) int64 {
@@ -208,7 +288,7 @@ func (gen *Generator) PerftTactical(depth int) int64 {
 	return movesCount
 }
 
-func (gen *Generator) PerftDivTactical(depth int)  {
+func (gen *Generator) PerftDivTactical(depth int, out io.Writer) {
 	var total int64 = 0
 	if depth <= 1 {
 		return
@@ -219,12 +299,12 @@ func (gen *Generator) PerftDivTactical(depth int)  {
 		subTotal := gen.PerftTactical(depth - 1)
 		total += subTotal
 		gen.PopMove()
-		fmt.Printf("%v: %d\n", move.mov, subTotal)
+		fmt.Fprintf(out, "%v: %d\n", move.mov, subTotal)
 	}
-	fmt.Println("total material-changing moves:", total)
+	fmt.Fprintln(out, "total material-changing moves:", total)
 }
 
-func (gen *Generator) Perftd(depth int) {
+func (gen *Generator) Perftd(depth int, out io.Writer) {
 	if depth == 0 {
 		return
 	}
@@ -234,20 +314,20 @@ func (gen *Generator) Perftd(depth int) {
 		gen.PushMove(move)
 		subTotal := gen.Perft(depth - 1)
 		total += subTotal
-		fmt.Printf("%v: %d\n", move, subTotal)
+		fmt.Fprintf(out, "%v: %d\n", move, subTotal)
 		gen.PopMove()
 	}
-	fmt.Println("total:", total)
+	fmt.Fprintln(out, "total:", total)
 }
 
-func (gen *Generator) Perftdd(depth int) {
+func (gen *Generator) Perftdd(depth int, out io.Writer) {
 	if depth <= 1 {
 		return
 	}
 	for _, rankedMove := range gen.GenerateMoves() {
 		move := rankedMove.mov
 		gen.PushMove(move)
-		fmt.Printf("Pushed %v: \n", move)
+		fmt.Fprintf(out, "Pushed %v: \n", move)
 
 		var sumOfPerft2LevsDown int64 = 0
 		if depth <= 1 {
@@ -256,13 +336,13 @@ func (gen *Generator) Perftdd(depth int) {
 		for _, rankedMovePrime := range gen.GenerateMoves() {
 			movePrime := rankedMovePrime.mov
 			gen.PushMove(movePrime)
-			fmt.Printf("\tPushed %v: \n", movePrime)
+			fmt.Fprintf(out, "\tPushed %v: \n", movePrime)
 			perft2 := gen.Perft(depth - 2)
 			sumOfPerft2LevsDown += perft2
-			fmt.Printf("\t%v: %d\n", movePrime, perft2)
+			fmt.Fprintf(out, "\t%v: %d\n", movePrime, perft2)
 			gen.PopMove()
 		}
-		fmt.Printf("%v: %d\n", move, sumOfPerft2LevsDown)
+		fmt.Fprintf(out, "%v: %d\n", move, sumOfPerft2LevsDown)
 		gen.PopMove()
 	}
 }
@@ -303,7 +383,7 @@ func (gen *Generator) generatePseudoLegalMoves() {
 		//pushes
 		to = from + square(pawnAdvanceDirection)
 		if pos.board[to] == NullPiece {
-			appendPawnPushes(from, to, promotionRank, pos.ply, outputMoves)
+			appendPawnPushes(from, to, promotionRank, outputMoves)
 			enPassantSquare := to
 			to = to + square(pawnAdvanceDirection)
 			if from.getRank() == pawnStartRank && pos.board[to] == NullPiece {
@@ -357,7 +437,7 @@ func (gen *Generator) generatePseudoLegalMoves() {
 			!pos.isUnderCheck(enemyPieces, enemyPawns, enemyKingSq, square(kingAsByte+dirAsByte)) &&
 			!pos.isUnderCheck(enemyPieces, enemyPawns, enemyKingSq, square(kingDest)) {
 			mov := NewMove(currentKingSq, square(kingDest))
-			*outputMoves = append(*outputMoves, rankedMove{mov, probeKillerMoves(mov, pos.ply), 0})
+			*outputMoves = append(*outputMoves, rankedMove{mov, 0, 0})
 		}
 	}
 	if kingsideCastlePossible {
@@ -369,7 +449,7 @@ func (gen *Generator) generatePseudoLegalMoves() {
 			!pos.isUnderCheck(enemyPieces, enemyPawns, enemyKingSq, square(kingAsByte+dirAsByte)) &&
 			!pos.isUnderCheck(enemyPieces, enemyPawns, enemyKingSq, square(kingDest)) {
 			mov := NewMove(currentKingSq, square(kingDest))
-			*outputMoves = append(*outputMoves, rankedMove{mov, probeKillerMoves(mov, pos.ply), 0})
+			*outputMoves = append(*outputMoves, rankedMove{mov, 0, 0})
 		}
 	}
 }
@@ -379,7 +459,7 @@ func appendMoveOrCapture(outputMoves *[]rankedMove, from, to square, pos *Positi
 	mov := NewMove(from, to)
 	attacked := pos.board[mov.to] & ColorlessPiece
 	if attacked == NullPiece {
-		*outputMoves = append(*outputMoves, rankedMove{mov, probeKillerMoves(mov, pos.ply), 0})
+		*outputMoves = append(*outputMoves, rankedMove{mov, 0, 0})
 		return
 	}
 	attacker := pos.board[mov.from] & ColorlessPiece
@@ -388,10 +468,10 @@ func appendMoveOrCapture(outputMoves *[]rankedMove, from, to square, pos *Positi
 		rankedMove{mov, captureRanking, mFlagTactical})
 }
 
-func appendSlidingPieceMoveOrCapture(outputMoves *[]rankedMove, from, to square, attacker, attacked piece, ply int16) {
+func appendSlidingPieceMoveOrCapture(outputMoves *[]rankedMove, from, to square, attacker, attacked piece) {
 	mov := NewMove(from, to)
 	if attacked == NullPiece {
-		*outputMoves = append(*outputMoves, rankedMove{mov, probeKillerMoves(mov, ply), 0})
+		*outputMoves = append(*outputMoves, rankedMove{mov, 0, 0})
 		return
 	}
 	captureRanking := int16(pieceToScore(attacked)-pieceToScore(attacker)) + rankingBonusTactical
@@ -404,16 +484,6 @@ func appendCapture(outputMoves *[]rankedMove, from, to square, attacker, attacke
 	*outputMoves = append(*outputMoves, rankedMove{mov, captureRanking, mFlagTactical})
 }
 
-func probeKillerMoves(mov Move, ply int16) int16 {
-	killers := killerMoves[ply]
-	if mov == killers[0] {
-		return rankingBonusKiller1st
-	} else if mov == killers[1] {
-		return rankingBonusKiller2nd
-	}
-	return 0
-}
-
 func (gen *Generator) generatePseudoLegalTacticalMoves() {
 	pos := gen.getTopPos()
 	var outputMoves *[]rankedMove = gen.getMovesFromTopPos()
@@ -446,7 +516,7 @@ func (gen *Generator) generatePseudoLegalTacticalMoves() {
 		// promoting pushes
 		to = from + square(pawnAdvanceDirection)
 		if pos.board[to] == NullPiece && to.getRank() == promotionRank {
-			appendPawnPushes(from, to, promotionRank, gen.getTopPos().ply, outputMoves)
+			appendPawnPushes(from, to, promotionRank, outputMoves)
 		}
 	}
 	for i := int8(0); i < currentPieces.size; i++ {
@@ -552,7 +622,7 @@ func (gen *Generator) String() string {
 	return gen.getTopPos().String()
 }
 
-func appendPawnPushes(from, to square, promotionRank rank, ply int16, outputMoves *[]rankedMove) {
+func appendPawnPushes(from, to square, promotionRank rank, outputMoves *[]rankedMove) {
 	if to.getRank() == promotionRank {
 		var commonPart int16 = rankingBonusTactical - MaterialPawnScore
 		*outputMoves = append(*outputMoves,
@@ -563,7 +633,7 @@ func appendPawnPushes(from, to square, promotionRank rank, ply int16, outputMove
 		)
 	} else {
 		mov := NewMove(from, to)
-		*outputMoves = append(*outputMoves, rankedMove{mov, probeKillerMoves(mov, ply), 0})
+		*outputMoves = append(*outputMoves, rankedMove{mov, 0, 0})
 	}
 }
 
@@ -591,7 +661,7 @@ func (pos *Position) appendSlidingPieceMoves(from square, currColorBit, enemyCol
 			if toContent&currColorBit != 0 {
 				break
 			}
-			appendSlidingPieceMoveOrCapture(outputMoves, from, to, attacker, toContent&ColorlessPiece, pos.ply)
+			appendSlidingPieceMoveOrCapture(outputMoves, from, to, attacker, toContent&ColorlessPiece)
 			if toContent&enemyColorBit != 0 {
 				break
 			}
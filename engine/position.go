@@ -67,6 +67,10 @@ type Position struct {
 	enPassSquare square
 	// zero based halfmove counter
 	ply			 int16
+	// number of plies since last capture or pawn move. Used for fifty-move rule
+	halfmoveClock int16
+	// zobrist hash of the position - see zobrist.go
+	hash         uint64
 }
 
 // used for position.flags
@@ -76,13 +80,16 @@ const (
 	FlagWhiteCanCastleQside
 	FlagBlackCanCastleKside
 	FlagBlackCanCastleQside
+
+	castlingFlagsMask = FlagWhiteCanCastleKside | FlagWhiteCanCastleQside |
+		FlagBlackCanCastleKside | FlagBlackCanCastleQside
 )
 
 // returns new starting position
 func NewPosition() Position {
 	// &Position{}  - shorthand for new Position on heap + return a pointer to it
 	z := InvalidSquare
-	return Position{
+	pos := Position{
 		board: [128]piece{
 			// FFS how do you turn off whitespace formatting in VSCode?
 			A1: WRook, B1: WKnight, C1: WBishop, D1: WQueen, E1: WKing, F1: WBishop, G1: WKnight, H1: WRook,
@@ -100,7 +107,8 @@ func NewPosition() Position {
 			FlagBlackCanCastleKside | FlagBlackCanCastleQside,
 		enPassSquare: InvalidSquare,
 	}
-
+	pos.hash = pos.computeHash()
+	return pos
 }
 
 // BUG/IDEA This string is too long to fit in 'debug watch' in VSCode. Not sure how to change cfg.
@@ -127,7 +135,7 @@ func (pos *Position) String() string {
 		pos.flags&FlagWhiteCanCastleKside != 0,
 		pos.flags&FlagWhiteTurn != 0)
 	sb.WriteString(fmt.Sprintf("WhiteKing: %v; WhitePieces: %v; WhitePawns: %v\n", pos.whiteKing, pos.whitePieces, pos.whitePawns))
-	sb.WriteString(fmt.Sprintf("En passant square: %v; ply: %d", pos.enPassSquare, pos.ply))
+	sb.WriteString(fmt.Sprintf("En passant square: %v; ply: %d; halfmove clock: %d", pos.enPassSquare, pos.ply, pos.halfmoveClock))
 	return sb.String()
 }
 
@@ -196,6 +204,24 @@ func (pos *Position) GetCurrentTacticalMoveContext() (
 		Rank8
 }
 
+// Passes the turn to the other side without moving anything - https://www.chessprogramming.org/Null_Move
+func (pos *Position) MakeNullMove() {
+	pos.ply++
+	// positions before the null move must not count as repetitions - see Generator.isRepetition()
+	pos.halfmoveClock = 0
+	pos.hash ^= zobristEnPassantKey(pos.enPassSquare) ^ zobristBlackTurn
+	pos.enPassSquare = InvalidSquare
+	pos.flags = pos.flags ^ FlagWhiteTurn
+}
+
+// Returns true if the side to move has nothing but king and pawns (zugzwang is likely then).
+func (pos *Position) hasOnlyPawnsLeft() bool {
+	if pos.flags&FlagWhiteTurn == 0 {
+		return pos.blackPieces.size == 0
+	}
+	return pos.whitePieces.size == 0
+}
+
 func (pos *Position) MakeMove(mov Move) (isLegal bool) {
 	currPieces, currPawnsPtr, currKingSq,
 		enemyPieces, enemyPawns, enemyKingSq,
@@ -204,9 +230,13 @@ func (pos *Position) MakeMove(mov Move) (isLegal bool) {
 		currColorBit, enemyColorBit := pos.getCurrentMakeMoveContext()
 	// pos.AssertConsistency("make" + mov.String())
 	pos.ply++
+	pos.halfmoveClock++
+	// xor out whatever might change. It's xored back in at the end of the method
+	pos.hash ^= zobristCastlingKey(pos.flags) ^ zobristEnPassantKey(pos.enPassSquare)
 
 	// one of thre possibilities - pawn move, king move, other piece move
 	if pos.board[mov.from] == Pawn|currColorBit {
+		pos.halfmoveClock = 0
 		// normal move - just update entry
 		if mov.promoteTo == NullPiece {
 			for i := int8(0); i < currPawnsPtr.size; i++ {
@@ -262,6 +292,8 @@ func (pos *Position) MakeMove(mov Move) (isLegal bool) {
 	}
 
 	if pos.board[mov.to] != NullPiece {
+		pos.halfmoveClock = 0
+		pos.hash ^= zobristPieceKey(pos.board[mov.to], mov.to)
 		// when calculating enemy mobility it is possible to kill enemy king.
 		// Kings are not on piece lists so we don't modify piece lists in MakeMove() and UnmakeMove()
 		if pos.board[mov.to] != King|enemyColorBit {
@@ -272,23 +304,28 @@ func (pos *Position) MakeMove(mov Move) (isLegal bool) {
 			}
 		}
 	}
+	pos.hash ^= zobristPieceKey(pos.board[mov.from], mov.from)
 	if mov.promoteTo == NullPiece {
 		pos.board[mov.to] = pos.board[mov.from]
 		//en passant take
 		if pos.enPassSquare == mov.to && pos.board[mov.from] == Pawn|currColorBit {
 			killSquare := square(mov.to.getFile() + file(mov.from.getRank()))
 			killPawn(enemyPawns, killSquare, nil)
+			pos.hash ^= zobristPieceKey(pos.board[killSquare], killSquare)
 			pos.board[killSquare] = NullPiece
 		}
 	} else {
 		pos.board[mov.to] = mov.promoteTo | currColorBit
 	}
+	pos.hash ^= zobristPieceKey(pos.board[mov.to], mov.to)
 	pos.board[mov.from] = NullPiece
 
 	// move mov was a double push
 	pos.enPassSquare = mov.enPassant
 
 	pos.flags = pos.flags ^ FlagWhiteTurn
+	pos.hash ^= zobristCastlingKey(pos.flags) ^ zobristEnPassantKey(pos.enPassSquare) ^ zobristBlackTurn
+	// pos.AssertHashConsistency("make" + mov.String())
 
 	// everything's been moved to it's place - time to check if it's actually legal
 	return !pos.isUnderCheck(*enemyPieces, *enemyPawns, enemyKingSq, *currKingSq)
@@ -492,7 +529,7 @@ func killPiece(enemyPieces *pieceList, killSquare square) {
 			return
 		}
 	}
-	panic(fmt.Sprintf("Didn't find square: %v on enemyPieces: %v", killSquare, enemyPieces))
+	panic(fmt.Sprintf("Didn't find square: %v on enemyPieces: %v", killSquare, *enemyPieces))
 }
 
 func killPawn(enemyPawns *pawnList, killSquare square, debugPos *Position) {
@@ -503,7 +540,12 @@ func killPawn(enemyPawns *pawnList, killSquare square, debugPos *Position) {
 			return
 		}
 	}
-	panic(fmt.Sprintf("Didn't find square: %v on enemyPieces: %v in position: %v", killSquare, enemyPawns, debugPos))
+	// print copies rather than pointers - otherwise every Position passed to MakeMove() escapes to heap
+	var debugPosStr string
+	if debugPos != nil {
+		debugPosStr = debugPos.String()
+	}
+	panic(fmt.Sprintf("Didn't find square: %v on enemyPieces: %v in position: %v", killSquare, *enemyPawns, debugPosStr))
 }
 
 // func killPieceOrdered(pieceList []square, killSquare square) []square {
@@ -525,6 +567,7 @@ func (pos *Position) moveRook(rookFrom, rookTo square, pieces *pieceList, colorB
 	}
 	pos.board[rookFrom] = NullPiece
 	pos.board[rookTo] = Rook | colorBit
+	pos.hash ^= zobristPieceKey(Rook|colorBit, rookFrom) ^ zobristPieceKey(Rook|colorBit, rookTo)
 }
 
 func (pos *Position) getCurrentMakeMoveContext() (
@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"strings"
+)
+
+// Standard Algebraic Notation -> https://www.chessprogramming.org/Algebraic_Chess_Notation#SAN
+// Returns legalMove (legal in the position on top of gen) in SAN - e.g. Nbd7, exd5, O-O, e8=Q+
+// Moves generated for the top position are generated again so slice returned by GenerateMoves() is overwritten.
+func (gen *Generator) toSan(legalMove Move) string {
+	pos := gen.getTopPos()
+	movingPiece := pos.board[legalMove.from] & ColorlessPiece
+	isCapture := pos.board[legalMove.to] != NullPiece ||
+		movingPiece == Pawn && legalMove.from.getFile() != legalMove.to.getFile()
+
+	var sb strings.Builder
+	switch {
+	case movingPiece == King && legalMove.to == legalMove.from+2:
+		sb.WriteString("O-O")
+	case movingPiece == King && legalMove.to+2 == legalMove.from:
+		sb.WriteString("O-O-O")
+	case movingPiece == Pawn:
+		if isCapture {
+			sb.WriteString(legalMove.from.String()[:1])
+			sb.WriteByte('x')
+		}
+		sb.WriteString(legalMove.to.String())
+		if legalMove.promoteTo != NullPiece {
+			sb.WriteByte('=')
+			sb.WriteString(sanPieceLetter(legalMove.promoteTo))
+		}
+	default:
+		sb.WriteString(sanPieceLetter(movingPiece))
+		sb.WriteString(gen.sanDisambiguation(legalMove, movingPiece))
+		if isCapture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(legalMove.to.String())
+	}
+
+	gen.PushMove(legalMove)
+	if gen.getTopPos().isCurrentKingUnderCheck() {
+		if len(gen.GenerateMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+	gen.PopMove()
+	return sb.String()
+}
+
+// Returns file and/or rank of legalMove.from when another piece of the same kind can move to the same square.
+func (gen *Generator) sanDisambiguation(legalMove Move, movingPiece piece) string {
+	pos := gen.getTopPos()
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range gen.GenerateMoves() {
+		from := other.mov.from
+		if other.mov.to != legalMove.to || from == legalMove.from || pos.board[from]&ColorlessPiece != movingPiece {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || from.getFile() == legalMove.from.getFile()
+		sameRank = sameRank || from.getRank() == legalMove.from.getRank()
+	}
+	from := legalMove.from.String()
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[:1]
+	case !sameRank:
+		return from[1:]
+	}
+	return from
+}
+
+func sanPieceLetter(p piece) string {
+	return strings.ToUpper(p.String())
+}
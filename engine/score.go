@@ -2,6 +2,7 @@ package engine
 
 import (
 	"fmt"
+	"io"
 	"math"
 )
 
@@ -10,6 +11,8 @@ const (
 	MinusInfinityScore = -InfinityScore
 	LostScore          = -100_000
 	DrawScore          = 0
+	// number of reversible plies after which game is drawn - fifty-move rule
+	FiftyMoveRulePlies = 100
 	ScoreCloseToMate   = 2 * (9*MaterialQueenScore + 2*MaterialRookScore +
 		2*MaterialBishopScore + 2*MaterialKnightScore)
 	// used to calc interpolation factor between mid/end game king-square tables
@@ -52,16 +55,14 @@ func pieceToScore(p piece) int {
 }
 
 // Returns fulll static evaluation score for Position pos. It's given relative to the currently playing
-// side (negamax score)
-func Evaluate(pos *Position, depth int, debug ...bool) int {
+// side (negamax score). Breakdown of the score is written to debug if given.
+func Evaluate(pos *Position, depth int, debug ...io.Writer) int {
 	return LazyEvaluate(pos, depth, MinusInfinityScore, InfinityScore, debug...)
 }
 
 // Returns static evaluation score for Position pos. It's given relative to the currently playingside (negamax score)
 // If the score is outsied <alpha-fullEvalScoreMargin, beta+fullEvalScoreMargin> window it skips costly part of evaluation.
-func LazyEvaluate(pos *Position, depth int, alpha, beta int, debug ...bool) int {
-	evaluatedNodes++
-
+func LazyEvaluate(pos *Position, depth int, alpha, beta int, debug ...io.Writer) int {
 	if isCheckMate(pos) {
 		return LostScore + depth
 	}
@@ -84,7 +85,7 @@ func LazyEvaluate(pos *Position, depth int, alpha, beta int, debug ...bool) int
 	pos.flags = pos.flags ^ FlagWhiteTurn
 	mobilityScore := currentMobilityScore - enemyMobilityScore
 	if len(debug) > 0 {
-		fmt.Println("gamePhaseFactor:", gamePhaseFactor,
+		fmt.Fprintln(debug[0], "gamePhaseFactor:", gamePhaseFactor,
 			"materialSquaresScore: ", materialSquaresScore,
 			"mobilityScore: ", mobilityScore)
 	}
@@ -102,7 +103,7 @@ func gamePhaseFactor(pos *Position) float64 {
 }
 
 // piece-square score from the perspective of side to move
-func pieceSquareScore(pos *Position, gamePhaseFactor float64, debug ...bool) int {
+func pieceSquareScore(pos *Position, gamePhaseFactor float64, debug ...io.Writer) int {
 	whiteScore := 0
 	for i := int8(0); i < pos.whitePieces.size; i++ {
 		pieceSquare := pos.whitePieces.squares[i]
@@ -170,8 +171,13 @@ func isCheckMate(position *Position) bool {
 	return position.isCurrentKingUnderCheck() && position.countMoves() == 0
 }
 
+// Returns true if the game is drawn due to fifty-move rule. Checkmate delivered with the last
+// move takes precedence over the rule.
+func isFiftyMoveRuleDraw(position *Position) bool {
+	return position.halfmoveClock >= FiftyMoveRulePlies && !isCheckMate(position)
+}
+
 func terminalNodeScore(position *Position, depth int) int {
-	evaluatedNodes++
 	if position.isCurrentKingUnderCheck() {
 		return LostScore + depth
 	}
@@ -357,4 +363,18 @@ func nonPawnMaterialScore(pieces pieceList, board *[128]piece) int {
 		}
 	}
 	return score
-}
\ No newline at end of file
+}
+
+// Returns chances (per mille) of win, draw and loss for the side to move with score. The model's constants
+// are picked by hand, not fitted to game results - see wdlMidpointScore.
+func winDrawLoss(score int) (win, draw, loss int) {
+	if closeToMate(score) {
+		if score > 0 {
+			return 1000, 0, 0
+		}
+		return 0, 0, 1000
+	}
+	win = int(math.Round(1000 / (1 + math.Exp((wdlMidpointScore-float64(score))/wdlScale))))
+	loss = int(math.Round(1000 / (1 + math.Exp((wdlMidpointScore+float64(score))/wdlScale))))
+	return win, 1000 - win - loss, loss
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestWinDrawLoss(t *testing.T) {
+	var tests = []struct {
+		score                    int
+		minWin, minDraw, minLoss int
+	}{
+		{0, 0, 700, 0},
+		{wdlMidpointScore, 500, 0, 0},
+		{-wdlMidpointScore, 0, 0, 500},
+		{10 * MaterialPawnScore, 990, 0, 0},
+		{-LostScore - 3, 1000, 0, 0},
+		{LostScore + 4, 0, 0, 1000},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.score), func(t *testing.T) {
+			win, draw, loss := winDrawLoss(test.score)
+			if win+draw+loss != 1000 || win < test.minWin || draw < test.minDraw || loss < test.minLoss {
+				t.Fatalf("unexpected win/draw/loss %v/%v/%v", win, draw, loss)
+			}
+			// the same chances from the other side of the board
+			if oppWin, oppDraw, oppLoss := winDrawLoss(-test.score); oppWin != loss || oppDraw != draw || oppLoss != win {
+				t.Fatalf("%v/%v/%v is not symmetrical to %v/%v/%v", win, draw, loss, oppWin, oppDraw, oppLoss)
+			}
+		})
+	}
+	prevWin := -1
+	for score := -500; score <= 500; score += 10 {
+		win, _, _ := winDrawLoss(score)
+		if win < prevWin {
+			t.Fatalf("chance of winning drops from %v to %v at score %v", prevWin, win, score)
+		}
+		prevWin = win
+	}
+}
@@ -1,11 +1,10 @@
 package engine
 
 import (
-	"fmt"
-	"os"
-	"runtime/pprof"
+	"math"
 	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,77 +20,166 @@ import (
 // [5]	   -> empty line - call Evaluate()
 // The algorithm for collecting PV is based on the one described here:
 // https://web.archive.org/web/20070808093935/http://www.brucemo.com/compchess/programming/pv.htm
+//
+// Each Search runs on its own goroutine (see SearchThreads) so everything it modifies while searching is its own.
 type Search struct {
 	bestLineAtDepth [MaxSearchDepth][]Move
-	stop            chan bool
-	interrupted     bool
+	// killerMoves [ply][]
+	killerMoves [][2]Move
+	// [whiteTurn][from][to] -> reward for beta cutoffs - see history.go
+	history [2][128][128]int32
+	// [from][to] of opponent's move -> quiet move that refuted it
+	counterMoves [128][128]Move
+	// [depth] -> move made on currently searched line. Move{} for null move
+	lineMoves [MaxSearchDepth]Move
+	// private copy of the position being searched
+	posGen *Generator
+	// read by the main search while the helpers are running
+	evaluatedNodes atomic.Int64
+	interrupted    bool
+	// number of isLimitReached() calls. The clock is read on every timeCheckInterval-th
+	limitChecks int
+	// deepest ply reached in the current iteration (seldepth)
+	selDepth int
+	// group that the search belongs to. All searches of the group stop at once
+	threads *SearchThreads
+	// 0 for the main search - the only one that prints info
+	id int
+
+	// empty line for searches that should not follow PV from the previous iteration
+	noCandidateLine Line
+	// best root moves of the current iteration with their exact scores - see startAlphaBeta()
+	multiPvLines []multiPvLine
+
+	// result of the deepest iteration completed so far
+	bestLine       *Line
+	bestScore      int
+	depthCompleted int
 }
-// killerMoves [ply][]
-var  killerMoves [][2]Move = make([][2]Move, killerMovesMaxPly)
 
-var evaluatedNodes int64
-var ProfileFile *os.File
+// root move with its score and line - one of the lines reported in MultiPV mode
+type multiPvLine struct {
+	score int
+	moves []Move
+}
 
-func NewSearch() *Search {
-	search := &Search{}
+func NewSearch(threads *SearchThreads, id int) *Search {
+	search := &Search{threads: threads, id: id}
 	for i := 0; i < len(search.bestLineAtDepth); i++ {
 		search.bestLineAtDepth[i] = make([]Move, MaxSearchDepth-i)
 	}
-	search.stop = make(chan bool)
+	search.killerMoves = make([][2]Move, killerMovesMaxPly)
+	search.bestLine = &Line{}
 	search.interrupted = true
 	return search
 }
 
-func (search *Search) StartIterativeDeepening(startTime, endTime time.Time, maxDepth int) {
-	if ProfileFile != nil {
-		fmt.Println("starting profiling")
-
-		pprof.StartCPUProfile(ProfileFile)
-		defer stopProfiling()
-	}
-	var bestLine *Line = &Line{}
+func (search *Search) iterativeDeepening(startTime time.Time, maxDepth int) {
 	search.interrupted = false
-	evaluatedNodes = 0
-	var bestScore int
-	var depthCompleted int = 1
+	search.evaluatedNodes.Store(0)
+	search.ageHistory()
+	search.depthCompleted = 1
+	search.selDepth = 0
 	var oneLegalMove bool
 
 	// first iteration outside of the loop so that it always returns some result - even at a time pressure.
 	// In extreme case engine would loose on time rather than crash trying to print out nil/uninitialized search result.
-	bestScore, oneLegalMove = search.startAlphaBeta(posGen, 1, &search.bestLineAtDepth[0],
-		bestLine, startTime, endTime)
-	copyBestLine(bestLine, search.bestLineAtDepth[0])
-
-	if !time.Now().After(endTime) && !search.interrupted && !oneLegalMove {
-		for currDepth := 2; currDepth <= maxDepth; currDepth++ {
-			var scoreAtDepth int
-			scoreAtDepth, oneLegalMove = search.startAlphaBeta(posGen, currDepth, &search.bestLineAtDepth[0],
-				bestLine, startTime, endTime)
-
-			if time.Now().After(endTime) {
-				break
-			}
-			if search.interrupted {
-				break
-			}
-		
-			copyBestLine(bestLine, search.bestLineAtDepth[0])
-			printInfoAfterDepth(scoreAtDepth, currDepth, bestLine.moves, time.Since(startTime), "")
-			depthCompleted = currDepth
-			bestScore = scoreAtDepth
-
-			// shortest mating line found - no need to go deeper
-			if pliesToMate(scoreAtDepth) == currDepth {
-				break
-			}
-			// skip deeper searches when only one move is possible
-			if oneLegalMove {
-				break
+	search.bestScore, oneLegalMove = search.startAlphaBeta(search.posGen, 1, &search.bestLineAtDepth[0],
+		search.bestLine, MinusInfinityScore, InfinityScore, startTime)
+	copyBestLine(search.bestLine, search.bestLineAtDepth[0])
+
+	// mated or stalemated - no move to search deeper
+	if len(search.bestLine.moves) == 0 {
+		return
+	}
+	if search.isStopped() || oneLegalMove && search.threads.mayEndEarly() {
+		return
+	}
+	// Helpers with odd id start one ply deeper so that searches do not walk the same tree in lockstep
+	for currDepth := 2 + search.id%2; currDepth <= min(maxDepth, MaxSearchDepth-1); currDepth++ {
+		var scoreAtDepth int
+		search.selDepth = 0
+		scoreAtDepth, oneLegalMove = search.aspirationSearch(currDepth, search.bestScore, startTime)
+
+		if search.threads.isLimitReached() {
+			break
+		}
+		if search.interrupted {
+			break
+		}
+
+		copyBestLine(search.bestLine, search.bestLineAtDepth[0])
+		if search.isMain() {
+			stats := search.threads.stats(startTime)
+			for i, line := range search.multiPvLines {
+				search.threads.publish(IterationEvent{SearchStats: stats, Depth: currDepth, SelDepth: search.reportedSelDepth(currDepth),
+					MultiPv: i + 1, Score: line.score, PV: line.moves})
 			}
 		}
+		search.depthCompleted = currDepth
+		search.bestScore = scoreAtDepth
+
+		// shortest mating line found - no need to go deeper
+		if pliesToMate(scoreAtDepth) == currDepth {
+			break
+		}
+		if search.threads.isMateLimitReached(scoreAtDepth) {
+			break
+		}
+		if search.isMain() && search.threads.isSoftLimitReached(search.bestLine.moves[0], scoreAtDepth) {
+			break
+		}
+		// skip deeper searches when only one move is possible
+		if oneLegalMove && search.threads.mayEndEarly() {
+			break
+		}
+	}
+}
+
+// Searches root with a narrow window around the score from previous iteration. The narrower the window
+// the more cutoffs. If the score falls outside of the window the search is repeated with a wider one.
+// https://www.chessprogramming.org/Aspiration_Windows
+func (search *Search) aspirationSearch(targetDepth, prevScore int, startTime time.Time) (score int, oneLegalMove bool) {
+	delta := aspirationWindowDelta
+	alpha, beta := MinusInfinityScore, InfinityScore
+	// multiple lines need exact scores of moves other than the best one - hard to guess the window for them
+	if !closeToMate(prevScore) && (search.threads.options.multiPV == 1 || !search.isMain()) {
+		alpha, beta = prevScore-delta, prevScore+delta
+	}
+	for {
+		search.bestLine.sublineLengthMatched = 0
+		score, oneLegalMove = search.startAlphaBeta(search.posGen, targetDepth, &search.bestLineAtDepth[0],
+			search.bestLine, alpha, beta, startTime)
+		if search.isStopped() {
+			return score, oneLegalMove
+		}
+
+		var bound ScoreBound
+		if score <= alpha && alpha > MinusInfinityScore {
+			bound = UpperBound
+		} else if score >= beta && beta < InfinityScore {
+			bound = LowerBound
+		} else {
+			return score, oneLegalMove
+		}
+		if search.isMain() {
+			// lines below the root move are not complete after cutoffs
+			search.threads.publish(PvEvent{SearchStats: search.threads.stats(startTime), Depth: targetDepth,
+				SelDepth: search.reportedSelDepth(targetDepth), Score: score, Bound: bound, PV: slices.Clone(search.getBestLine()[:1])})
+		}
+		delta *= 2
+		if delta > aspirationWindowMaxDelta {
+			alpha, beta = MinusInfinityScore, InfinityScore
+		} else if bound == UpperBound {
+			alpha = max(score-delta, MinusInfinityScore)
+		} else {
+			beta = min(score+delta, InfinityScore)
+		}
 	}
-	printInfo(bestScore, depthCompleted, bestLine.moves, time.Since(startTime), "")
-	fmt.Println("bestmove", bestLine.moves[0])
+}
+
+func (search *Search) isMain() bool {
+	return search.id == 0
 }
 
 func copyBestLine(bestLineDst *Line, bestLineSrc []Move) {
@@ -119,71 +207,271 @@ func (pv *Search) getBestLine() []Move {
 // Param candidateLine stores line that should be evaluated first by the search
 // (TODO - make candidateLine a list of candidate lines - so far it seems to be duplicating
 // work of currBestLine
+// Param nullMoveAllowed is false right after the null move - two null moves in a row prove nothing.
 func (search *Search) alphaBeta(aPosGen *Generator, targetDepth, depth, alpha, beta int,
-	currBestLine *[]Move, candidateLine *Line, startTime, endTime time.Time) int {
+	currBestLine *[]Move, candidateLine *Line, nullMoveAllowed bool, startTime time.Time) int {
+	if aPosGen.isRepetition() {
+		*currBestLine = (*currBestLine)[:0]
+		return DrawScore
+	}
+	search.selDepth = max(search.selDepth, depth)
+	pos := aPosGen.getTopPos()
+	inCheck := pos.isCurrentKingUnderCheck()
+	// Check extension -> https://www.chessprogramming.org/Check_Extensions
+	// Escapes from check are searched one ply deeper so that mating attacks are not cut off by the horizon
+	// (quiescence looks at captures only). Line buffers end at MaxSearchDepth so the extensions stop there.
+	if inCheck && targetDepth < MaxSearchDepth-1 {
+		targetDepth++
+	}
+	if depth >= targetDepth {
+		return search.quiescence(aPosGen, alpha, beta, depth, currBestLine, startTime)
+	}
 	bestSubline := search.bestLineAtDepth[depth+1]
-	if targetDepth == depth {
-		return search.quiescence(aPosGen, alpha, beta, depth, currBestLine, startTime, endTime)
+
+	if isFiftyMoveRuleDraw(pos) {
+		*currBestLine = (*currBestLine)[:0]
+		return DrawScore
+	}
+	// Mate distance pruning -> https://www.chessprogramming.org/Mate_Distance_Pruning
+	// Even being mated right here is better than beta - or mating with the next move is worse than alpha
+	// (a shorter mate has been found already).
+	if LostScore+depth >= beta {
+		return beta
+	}
+	if -LostScore-depth-1 <= alpha {
+		return alpha
+	}
+	remainingDepth := targetDepth - depth
+	entry, found := search.threads.transpositionTable.probe(pos.hash)
+	// cutoff in PV node (full window) would cut the principal variation short - the entry has no line stored
+	isPvNode := beta-alpha > 1
+	if found && !isPvNode && int(entry.depth) >= remainingDepth {
+		if ttScore, ok := entry.cutoffScore(alpha, beta, depth); ok {
+			*currBestLine = (*currBestLine)[:0]
+			return ttScore
+		}
+	}
+
+	if nullMoveAllowed && search.isNullMovePruningAllowed(pos, inCheck, remainingDepth, beta) {
+		reduction := nullMoveReduction(remainingDepth)
+		search.lineMoves[depth] = Move{}
+		aPosGen.PushNullMove()
+		nullMoveScore := -search.alphaBeta(aPosGen, targetDepth-reduction, depth+1, -beta, -beta+1, &bestSubline,
+			&search.noCandidateLine, false, startTime)
+		aPosGen.PopMove()
+		// side to move is so good that it could pass and still exceed beta
+		if nullMoveScore >= beta {
+			return beta
+		}
 	}
 
 	moves := aPosGen.GenerateMoves()
 
 	if len(moves) == 0 {
 		*currBestLine = (*currBestLine)[:0]
-		return terminalNodeScore(aPosGen.getTopPos(), depth)
+		search.evaluatedNodes.Add(1)
+		return terminalNodeScore(pos, depth)
 	}
 
+	prevMove := search.previousMove(depth)
+	search.applyHistoryRanking(moves, pos, prevMove)
+	search.applyKillerMoveBonus(moves, pos.ply)
 	applyPvMoveBonus(moves, candidateLine, depth)
+	if found {
+		applyHashMoveBonus(moves, entry.move)
+	}
 	sortMoves(moves)
-	for _, move := range moves {
+	alphaAtStart := alpha
+	var bestMove Move
+	for i, move := range moves {
 		if search.interrupted {
 			break
 		}
+		search.lineMoves[depth] = move.mov
 		aPosGen.PushMove(move.mov)
-		currScore := -search.alphaBeta(aPosGen, targetDepth, depth+1, -beta, -alpha, &bestSubline,
-			candidateLine, startTime, endTime)
+		var currScore int
+		// Principal Variation Search -> https://www.chessprogramming.org/Principal_Variation_Search
+		// Moves are sorted so the first one is expected to be the best. Others only need to be proven worse
+		// with a cheap zero-width window. Full search is repeated only for the ones that turn out better.
+		if i == 0 {
+			currScore = -search.alphaBeta(aPosGen, targetDepth, depth+1, -beta, -alpha, &bestSubline,
+				candidateLine, true, startTime)
+		} else {
+			reduction := 0
+			if !inCheck && isLateMove(move) && remainingDepth >= lmrMinDepth &&
+				!aPosGen.getTopPos().isCurrentKingUnderCheck() {
+				reduction = lateMoveReduction(remainingDepth, i)
+			}
+			currScore = -search.alphaBeta(aPosGen, targetDepth-reduction, depth+1, -alpha-1, -alpha, &bestSubline,
+				candidateLine, true, startTime)
+			if currScore > alpha && reduction > 0 {
+				currScore = -search.alphaBeta(aPosGen, targetDepth, depth+1, -alpha-1, -alpha, &bestSubline,
+					candidateLine, true, startTime)
+			}
+			if currScore > alpha && currScore < beta {
+				currScore = -search.alphaBeta(aPosGen, targetDepth, depth+1, -beta, -alpha, &bestSubline,
+					candidateLine, true, startTime)
+			}
+		}
 		aPosGen.PopMove()
 
 		if currScore >= beta {
 			if move.flags & mFlagTactical == 0 {
-				updateKillerMoves(aPosGen.getTopPos().ply, move.mov)
+				search.updateKillerMoves(pos.ply, move.mov)
+				search.updateHistory(pos, remainingDepth, move.mov, prevMove)
+			}
+			if !search.isStopped() {
+				search.threads.transpositionTable.store(pos.hash, remainingDepth, depth, boundLower, beta, move.mov)
 			}
 			return beta
 		}
 		if currScore > alpha {
 			updateBestLine(currBestLine, bestSubline, move.mov)
 			alpha = currScore
+			bestMove = move.mov
 		}
-		if search.interrupted || time.Now().After(endTime) {
+		if search.isStopped() {
 			break
 		}
-		select {
-		case <-search.stop:
-			search.interrupted = true
-		default:
-		}
 	}
 
+	if !search.isStopped() {
+		if alpha > alphaAtStart {
+			search.threads.transpositionTable.store(pos.hash, remainingDepth, depth, boundExact, alpha, bestMove)
+		} else {
+			search.threads.transpositionTable.store(pos.hash, remainingDepth, depth, boundUpper, alpha, Move{})
+		}
+	}
 	return alpha
 }
 
-func updateKillerMoves(currPly int16, move Move) {
-	killerMoves[currPly][1] = killerMoves[currPly][0]
-	killerMoves[currPly][0] = move
+// Null move pruning -> https://www.chessprogramming.org/Null_Move_Pruning
+// Passing the turn is illegal when in check. It's also the best 'move' in zugzwang (that's likely
+// in pawn endgames) so there the null move would wrongly prove that the position is good.
+func (search *Search) isNullMovePruningAllowed(pos *Position, inCheck bool, remainingDepth, beta int) bool {
+	return search.threads.options.nullMovePruning &&
+		remainingDepth >= nullMoveMinDepth &&
+		// no point proving that the position is at least a mate
+		beta < ScoreCloseToMate &&
+		!pos.hasOnlyPawnsLeft() &&
+		!inCheck
+}
+
+// Returns by how many plies search after null move is shallower than the normal one.
+func nullMoveReduction(remainingDepth int) int {
+	if remainingDepth > 6 {
+		return 3
+	}
+	return 2
+}
+
+// Late move reductions -> https://www.chessprogramming.org/Late_Move_Reductions
+// Quiet move that was ranked below killers is unlikely to be any good. It's enough to search it at
+// reduced depth to prove that. Moves that turn out to beat alpha are searched again at full depth.
+func isLateMove(move rankedMove) bool {
+	return move.flags&mFlagTactical == 0 && move.ranking < rankingBonusKiller2nd
+}
+
+// [remainingDepth][moveIdx] -> plies to reduce. Grows slowly both with depth and move number
+var lateMoveReductions [MaxSearchDepth + 1][moveBufferCapacity]int
+
+func init() {
+	for depth := 1; depth < len(lateMoveReductions); depth++ {
+		for moveIdx := 1; moveIdx < len(lateMoveReductions[depth]); moveIdx++ {
+			lateMoveReductions[depth][moveIdx] = int(lmrBase + math.Log(float64(depth))*math.Log(float64(moveIdx))/lmrDivisor)
+		}
+	}
+}
+
+// Returns how many plies less to search moveIdx-th move. Reduced search goes at least one ply
+// deeper than the quiescence.
+func lateMoveReduction(remainingDepth, moveIdx int) int {
+	reduction := lateMoveReductions[min(remainingDepth, MaxSearchDepth)][min(moveIdx, moveBufferCapacity-1)]
+	return min(max(reduction, 1), remainingDepth-2)
+}
+
+// Results of interrupted search are garbage. They must not be used nor stored in transposition table.
+// Checked at every node so that 'stop' is obeyed within a few nodes.
+func (search *Search) isStopped() bool {
+	if !search.interrupted && (search.threads.stop.Load() || search.isLimitReached()) {
+		search.interrupted = true
+	}
+	return search.interrupted
+}
+
+// Returns true when the search evaluated its share of the node limit or ran out of time. Touches only
+// the memory of this search - but for every timeCheckInterval-th call that reads the clock.
+func (search *Search) isLimitReached() bool {
+	threads := search.threads
+	if threads.maxNodesPerSearch > 0 && search.evaluatedNodes.Load() >= threads.maxNodesPerSearch {
+		return true
+	}
+	search.limitChecks++
+	if search.limitChecks%timeCheckInterval != 0 {
+		return false
+	}
+	return threads.clock.Now().UnixNano() > threads.endTime.Load()
+}
+
+// Returns seldepth of the iteration searched to depth. Lines cut off by transposition table, repetitions
+// or mates end before the horizon but the iteration still searched to depth.
+func (search *Search) reportedSelDepth(depth int) int {
+	return max(search.selDepth, depth)
+}
+
+func (search *Search) updateKillerMoves(currPly int16, move Move) {
+	search.killerMoves[currPly][1] = search.killerMoves[currPly][0]
+	search.killerMoves[currPly][0] = move
+}
+
+// Killer moves are quiet moves that caused beta cutoff in other positions at the same ply
+// https://www.chessprogramming.org/Killer_Heuristic
+func (search *Search) applyKillerMoveBonus(moves []rankedMove, currPly int16) {
+	killers := search.killerMoves[currPly]
+	for i, m := range moves {
+		if m.flags&mFlagTactical != 0 {
+			continue
+		}
+		if m.mov == killers[0] {
+			moves[i].ranking = rankingBonusKiller1st
+		} else if m.mov == killers[1] {
+			moves[i].ranking = rankingBonusKiller2nd
+		}
+	}
+}
+
+func (search *Search) clearKillerMoves() {
+	clear(search.killerMoves)
 }
 
+// Searches root moves within <windowAlpha, beta> window. When MultiPV is set the main search finds exact
+// scores and lines of the best multiPV moves (stored in search.multiPvLines) by searching each root move
+// with alpha set to the score of the worst line kept so far.
 func (search *Search) startAlphaBeta(aPosGen *Generator, targetDepth int, currBestLine *[]Move,
-	pvLine *Line, starttime, endtime time.Time) (score int, oneLegalMove bool) {
+	pvLine *Line, windowAlpha, beta int, starttime time.Time) (score int, oneLegalMove bool) {
 	bestSubline := search.bestLineAtDepth[1]
 	moves := aPosGen.GenerateMoves()
-	alpha := MinusInfinityScore
-	beta := InfinityScore
+	bestScore := MinusInfinityScore
+	search.multiPvLines = search.multiPvLines[:0]
 
 	if len(moves) == 0 {
 		*currBestLine = (*currBestLine)[:0]
+		search.evaluatedNodes.Add(1)
 		return terminalNodeScore(aPosGen.getTopPos(), 0), false
 	}
+	// restricting the search to one of many legal moves doesn't make the move forced
+	oneLegalMove = len(moves) == 1
+	if len(search.threads.limits.searchMoves) > 0 {
+		moves = search.threads.filterSearchMoves(moves)
+	}
+	// helpers only feed the transposition table - no need to spend time on additional lines
+	linesCount := 1
+	if search.isMain() {
+		linesCount = min(search.threads.options.multiPV, len(moves))
+	}
 
+	search.applyHistoryRanking(moves, aPosGen.getTopPos(), Move{})
+	search.applyKillerMoveBonus(moves, aPosGen.getTopPos().ply)
 	applyPvMoveBonus(moves, pvLine, 0)
 	sortMoves(moves)
 	for aPosGen.firstMoveIdx = 0; aPosGen.firstMoveIdx < len(moves); (aPosGen.firstMoveIdx)++ {
@@ -191,33 +479,77 @@ func (search *Search) startAlphaBeta(aPosGen *Generator, targetDepth int, currBe
 		if search.interrupted {
 			break
 		}
+		alpha := max(windowAlpha, search.multiPvAlpha(linesCount))
+		// GUI shows which move is being searched once the search takes long
+		if search.isMain() && search.threads.since(starttime) >= currMoveInfoMinTime {
+			search.threads.publish(CurrMoveEvent{SearchStats: search.threads.stats(starttime), Depth: targetDepth,
+				Move: move.mov, Number: aPosGen.firstMoveIdx + 1})
+		}
+		search.lineMoves[0] = move.mov
 		aPosGen.PushMove(move.mov)
-		currScore := -search.alphaBeta(aPosGen, targetDepth, 1, -beta, -alpha, &bestSubline,
-			pvLine, starttime, endtime)
+		var currScore int
+		// PVS just like in alphaBeta()
+		if aPosGen.firstMoveIdx == 0 || alpha == MinusInfinityScore {
+			currScore = -search.alphaBeta(aPosGen, targetDepth, 1, -beta, -alpha, &bestSubline,
+				pvLine, true, starttime)
+		} else {
+			currScore = -search.alphaBeta(aPosGen, targetDepth, 1, -alpha-1, -alpha, &bestSubline,
+				pvLine, true, starttime)
+			if currScore > alpha && currScore < beta {
+				currScore = -search.alphaBeta(aPosGen, targetDepth, 1, -beta, -alpha, &bestSubline,
+					pvLine, true, starttime)
+			}
+		}
 		aPosGen.PopMove()
 
 		if currScore > alpha {
+			search.addMultiPvLine(linesCount, currScore, move.mov, bestSubline)
+		}
+		if currScore > bestScore {
 			updateBestLine(currBestLine, bestSubline, move.mov)
-			alpha = currScore
+			bestScore = currScore
 
-			maybePrintNewPvInfo(alpha, targetDepth, search.getBestLine(), time.Duration(time.Since(starttime)), "")
-			// printInfo( alpha, targetDepth, search.getBestLine(), time.Duration(time.Since(starttime)), "in startAB:")
+			// only exact scores of completed moves are worth printing
+			if search.isMain() && currScore > alpha && currScore < beta && !search.isStopped() {
+				search.threads.publish(PvEvent{SearchStats: search.threads.stats(starttime), Depth: targetDepth,
+					SelDepth: search.reportedSelDepth(targetDepth), Score: bestScore, Bound: ExactScore, PV: slices.Clone(search.getBestLine())})
+			}
 		}
-		if search.interrupted || time.Now().After(endtime) {
+		if search.isStopped() {
 			break
 		}
-		if nextMoveWins(currScore) {
+		if nextMoveWins(currScore) && linesCount == 1 {
 			break
 		}
-
-		select {
-		case <-search.stop:
-			search.interrupted = true
-		default:
+		// fail high - aspirationSearch() will repeat the search with a wider window
+		if currScore >= beta {
+			break
 		}
 	}
 
-	return alpha, len(moves) == 1
+	return bestScore, oneLegalMove
+}
+
+// Returns lower bound of the window that root moves are searched with. Root move needs to beat it to be
+// one of the linesCount best moves.
+func (search *Search) multiPvAlpha(linesCount int) int {
+	if len(search.multiPvLines) < linesCount {
+		return MinusInfinityScore
+	}
+	return search.multiPvLines[linesCount-1].score
+}
+
+// Inserts line starting with rootMove keeping multiPvLines sorted by score (best first) and at most linesCount long.
+func (search *Search) addMultiPvLine(linesCount, score int, rootMove Move, subline []Move) {
+	line := multiPvLine{score: score, moves: append([]Move{rootMove}, subline...)}
+	idx := len(search.multiPvLines)
+	for idx > 0 && search.multiPvLines[idx-1].score < score {
+		idx--
+	}
+	search.multiPvLines = slices.Insert(search.multiPvLines, idx, line)
+	if len(search.multiPvLines) > linesCount {
+		search.multiPvLines = search.multiPvLines[:linesCount]
+	}
 }
 
 func nextMoveWins(score int) bool {
@@ -228,16 +560,26 @@ func nextMoveWins(score int) bool {
 func applyPvMoveBonus(moves []rankedMove, candidateLine *Line, depth int) {
 	for i, m := range moves {
 		if candidateLine.isMoveOnLine(m.mov, depth) {
-			moves[i].ranking += rankingBonusPvMove
+			moves[i].ranking = rankingBonusPvMove
+			break
+		}
+	}
+}
+func applyHashMoveBonus(moves []rankedMove, hashMove Move) {
+	for i, m := range moves {
+		if m.mov == hashMove {
+			moves[i].ranking = rankingBonusHashMove
 			break
 		}
 	}
 }
+
 func sortMoves(moves []rankedMove) {
 	slices.SortFunc(moves,
 		//desc sort by ranking
 		func(a, b rankedMove) int {
-			return int(b.ranking - a.ranking)
+			// int16 subtraction would overflow with losing captures
+			return int(b.ranking) - int(a.ranking)
 		})
 }
 
@@ -248,52 +590,73 @@ func updateBestLine(currBestLine *[]Move, betterSubline []Move, betterMove Move)
 }
 
 func (search *Search) quiescence(aPosGen *Generator, alpha, beta, depth int,
-	currBestLine *[]Move, startTime, endTime time.Time) int {
+	currBestLine *[]Move, startTime time.Time) int {
+	search.selDepth = max(search.selDepth, depth)
+	pos := aPosGen.getTopPos()
+	if isFiftyMoveRuleDraw(pos) {
+		*currBestLine = (*currBestLine)[:0]
+		return DrawScore
+	}
+	// no room for longer lines - stand pat
+	if depth >= MaxSearchDepth-1 {
+		*currBestLine = (*currBestLine)[:0]
+		search.evaluatedNodes.Add(1)
+		return min(max(LazyEvaluate(pos, depth, alpha, beta), alpha), beta)
+	}
 	bestSubline := search.bestLineAtDepth[depth+1]
-	score := LazyEvaluate(aPosGen.getTopPos(), depth, alpha, beta)
-
-	if evaluatedNodes%int64(currmoveLogInterval) == 0 {
-		currMoveNo := aPosGen.firstMoveIdx
-		timeElapsed := time.Since(startTime)
-		fmt.Println("info",
-			"currmove", aPosGen.movStack[0][currMoveNo].mov,
-			"currmovenumber", currMoveNo+1,
-			"nodes", evaluatedNodes,
-			"time", timeElapsed.Milliseconds(),
-			"nps", nps(evaluatedNodes, timeElapsed))
+	entry, found := search.threads.transpositionTable.probe(pos.hash)
+	if found {
+		if ttScore, ok := entry.cutoffScore(alpha, beta, depth); ok {
+			*currBestLine = (*currBestLine)[:0]
+			return ttScore
+		}
 	}
+	score := LazyEvaluate(pos, depth, alpha, beta)
+	search.evaluatedNodes.Add(1)
 
 	if score >= beta {
+		search.threads.transpositionTable.store(pos.hash, 0, depth, boundLower, beta, Move{})
 		return beta
 	}
+	alphaAtStart := alpha
 	if score > alpha {
 		//TODO should add updateBestLine() here?
 		*currBestLine = (*currBestLine)[:0]
 		alpha = score
 	}
 	tacticalMoves := aPosGen.GenerateTacticalMoves()
+	if found {
+		applyHashMoveBonus(tacticalMoves, entry.move)
+	}
 	sortMoves(tacticalMoves)
+	var bestMove Move
 	for _, mov := range tacticalMoves {
+		// can't raise alpha - standing pat is better
+		if mov.flags&mFlagLosingCapture != 0 {
+			continue
+		}
 		aPosGen.PushMove(mov.mov)
-		score = -search.quiescence(aPosGen, -beta, -alpha, depth+1, &bestSubline, startTime, endTime)
+		score = -search.quiescence(aPosGen, -beta, -alpha, depth+1, &bestSubline, startTime)
 		aPosGen.PopMove()
 
-		if search.interrupted || time.Now().After(endTime) {
-			break
+		if search.isStopped() {
+			return alpha
 		}
 
 		if score >= beta {
+			search.threads.transpositionTable.store(pos.hash, 0, depth, boundLower, beta, mov.mov)
 			return beta
 		}
 		if score > alpha {
 			updateBestLine(currBestLine, bestSubline, mov.mov)
 			alpha = score
+			bestMove = mov.mov
 		}
 	}
+	if alpha > alphaAtStart {
+		search.threads.transpositionTable.store(pos.hash, 0, depth, boundExact, alpha, bestMove)
+	} else {
+		search.threads.transpositionTable.store(pos.hash, 0, depth, boundUpper, alpha, Move{})
+	}
 	return alpha
 }
-
-func stopProfiling() {
-	fmt.Println("iterative deepening: stopping profiling.....")
-	pprof.StopCPUProfile()
-}
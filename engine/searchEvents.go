@@ -0,0 +1,86 @@
+package engine
+
+import "time"
+
+// Receives events published by the search. OnSearchEvent() is called on the search goroutine so it must
+// return quickly - every moment spent in it is taken from the search. Events are one of IterationEvent,
+// PvEvent, CurrMoveEvent or BestMoveEvent.
+type SearchListener interface {
+	OnSearchEvent(event SearchEvent)
+}
+
+type SearchEvent interface {
+	searchEvent()
+}
+
+// Progress of the whole group of searches at the moment of the event
+type SearchStats struct {
+	Nodes int64
+	Time  time.Duration
+	// per mille of the transposition table in use
+	Hashfull int
+}
+
+// Kind of the score of the line. Only iterations that fell outside of the aspiration window have
+// inexact scores - see aspirationSearch().
+type ScoreBound int
+
+const (
+	ExactScore ScoreBound = iota
+	// the real score is at least that
+	LowerBound
+	// the real score is at most that
+	UpperBound
+)
+
+// Published for every line (MultiPV) once the iteration is completed. Scores in all the events are in
+// centipawns from the side to move point of view - see closeToMate() for mate scores.
+type IterationEvent struct {
+	SearchStats
+	Depth int
+	// deepest ply reached (including quiescence search)
+	SelDepth int
+	// 1 for the best line
+	MultiPv int
+	Score   int
+	PV      []Move
+}
+
+// Published when a new best line is found while the iteration is still running.
+type PvEvent struct {
+	SearchStats
+	Depth    int
+	SelDepth int
+	Score    int
+	Bound    ScoreBound
+	PV       []Move
+}
+
+// Published when the search of a root move starts - once the search has been running for currMoveInfoMinTime.
+type CurrMoveEvent struct {
+	SearchStats
+	Depth int
+	Move  Move
+	// 1 for the first root move searched
+	Number int
+}
+
+// Published once the search is done. Ends the events of the search.
+type BestMoveEvent struct {
+	SearchStats
+	// deepest iteration completed
+	Depth    int
+	SelDepth int
+	Score    int
+	// principal variation - starts with Move
+	PV []Move
+	// Move{} when there is no legal move in the position
+	Move Move
+	// expected reply to Move. Move{} unless Ponder option is on and PV is long enough
+	Ponder Move
+}
+
+func (IterationEvent) searchEvent() {}
+func (PvEvent) searchEvent()        {}
+func (CurrMoveEvent) searchEvent()  {}
+func (BestMoveEvent) searchEvent()  {}
@@ -0,0 +1,281 @@
+package engine
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lazy SMP -> https://www.chessprogramming.org/Lazy_SMP
+// Every search of the group runs iterative deepening from the same position on its own goroutine.
+// Searches share nothing but the transposition table - helpers fill it with results that the main
+// search (searches[0]) can cut off on. Only the main search publishes events while searching.
+type SearchThreads struct {
+	searches []*Search
+	// set when all searches should stop
+	stop atomic.Bool
+	// closed by 'ponderhit' or 'stop' - search that has to run until then waits for it once done
+	released    chan struct{}
+	releaseOnce sync.Once
+	// deadline of the search in unix nanoseconds. Moved by ponderhit while searching
+	endTime atomic.Int64
+	// when the engine's clock started ticking in unix nanoseconds. Moved by ponderhit while searching
+	clockStartTime atomic.Int64
+	// updated only by the main search
+	timeManager timeManager
+	// set while searching on opponent's time. Search must not print bestmove until ponderhit or stop
+	pondering atomic.Bool
+	// limits from 'go' command other than time. Not modified while searching
+	limits searchLimits
+	// every search stops after evaluating its share of the node limit. 0 for no limit
+	maxNodesPerSearch int64
+	// all the timing of the search is measured with it
+	clock Clock
+	// shared by all searches of the group
+	transpositionTable *TranspositionTable
+	// options of the engine at the start of the search. Not modified while searching
+	options options
+	// receive events of the search. Not modified while searching
+	listeners []SearchListener
+}
+
+// Limits of the search given in 'go' command
+type searchLimits struct {
+	startTime time.Time
+	// hard limit of the search
+	endTime time.Time
+	// plans time for the move when playing on the clock. Zero value when the search may use all time till endTime
+	timeManager timeManager
+	// search on opponent's time - see SearchThreads.PonderHit()
+	pondering bool
+	// 'go infinite' - search until 'stop'
+	infinite bool
+	maxDepth int
+	// stop after that many evaluated nodes. 0 for no limit
+	maxNodes int64
+	// stop once mate in that many moves (or less) is found. 0 for no limit
+	mateIn int
+	// root moves to choose from. Empty for all legal moves
+	searchMoves []Move
+}
+
+func newSearchLimits(endTime time.Time) searchLimits {
+	return searchLimits{endTime: endTime, maxDepth: MaxSearchDepth}
+}
+
+// Returns group of threadsCount searches that share transpositionTable.
+func NewSearchThreads(threadsCount int, transpositionTable *TranspositionTable) *SearchThreads {
+	threads := &SearchThreads{
+		clock:              systemClock{},
+		transpositionTable: transpositionTable,
+		options:            defaultOptions(),
+		released:           make(chan struct{}),
+	}
+	threads.setThreadsCount(threadsCount)
+	return threads
+}
+
+// Adds or removes helper searches so that the group has threadsCount of them. Searches that remain keep
+// their history, countermoves and killer moves. Must not be called while searching.
+func (threads *SearchThreads) setThreadsCount(threadsCount int) {
+	if threadsCount < len(threads.searches) {
+		clear(threads.searches[threadsCount:])
+		threads.searches = threads.searches[:threadsCount]
+	}
+	for id := len(threads.searches); id < threadsCount; id++ {
+		threads.searches = append(threads.searches, NewSearch(threads, id))
+	}
+}
+
+// Gives every search its own copy of the position held by gen. Must be called before the search starts
+// (not on the search goroutine) so that 'stop' sent right after 'go' is not lost.
+func (threads *SearchThreads) SetPosition(gen *Generator) {
+	threads.stop.Store(false)
+	threads.released = make(chan struct{})
+	threads.releaseOnce = sync.Once{}
+	for _, search := range threads.searches {
+		search.posGen = gen.clone()
+	}
+}
+
+// Sets options of the next search. Like SetPosition() it must be called before the search starts.
+func (threads *SearchThreads) setOptions(options options) {
+	threads.options = options
+}
+
+// Sets listeners of the next search. Like SetPosition() it must be called before the search starts.
+func (threads *SearchThreads) setListeners(listeners []SearchListener) {
+	threads.listeners = listeners
+}
+
+func (threads *SearchThreads) publish(event SearchEvent) {
+	for _, listener := range threads.listeners {
+		listener.OnSearchEvent(event)
+	}
+}
+
+// Returns progress of the group since startTime.
+func (threads *SearchThreads) stats(startTime time.Time) SearchStats {
+	return SearchStats{
+		Nodes:    threads.evaluatedNodes(),
+		Time:     threads.since(startTime),
+		Hashfull: threads.transpositionTable.hashfull(),
+	}
+}
+
+// Sets limits of the next search. Like SetPosition() it must be called before the search starts.
+func (threads *SearchThreads) setLimits(limits searchLimits) {
+	threads.limits = limits
+	threads.maxNodesPerSearch = 0
+	if limits.maxNodes > 0 {
+		// counters of other searches are not read while searching - they are written by other cores
+		threads.maxNodesPerSearch = limits.maxNodes / int64(len(threads.searches))
+		if threads.maxNodesPerSearch < 1 {
+			threads.maxNodesPerSearch = 1
+		}
+	}
+	threads.endTime.Store(limits.endTime.UnixNano())
+	threads.clockStartTime.Store(limits.startTime.UnixNano())
+	threads.timeManager = limits.timeManager
+	threads.pondering.Store(limits.pondering)
+}
+
+// Replaces the system clock. Must not be called while searching.
+func (threads *SearchThreads) SetClock(clock Clock) {
+	threads.clock = clock
+}
+
+// Returns time elapsed since t according to the clock of the group.
+func (threads *SearchThreads) since(t time.Time) time.Duration {
+	return threads.clock.Now().Sub(t)
+}
+
+// Turns pondering search into a normal one. Time planned for the move starts ticking at now.
+func (threads *SearchThreads) PonderHit(now time.Time) {
+	threads.clockStartTime.Store(now.UnixNano())
+	threads.endTime.Store(now.Add(threads.limits.timeManager.maximum).UnixNano())
+	threads.pondering.Store(false)
+	if !threads.limits.infinite {
+		threads.release()
+	}
+}
+
+// Lets the search that is done end - see search().
+func (threads *SearchThreads) release() {
+	threads.releaseOnce.Do(func() { close(threads.released) })
+}
+
+// Returns false while the search has to run until 'stop' (or 'ponderhit') comes - infinite and pondering ones.
+func (threads *SearchThreads) mayEndEarly() bool {
+	return !threads.limits.infinite && !threads.pondering.Load()
+}
+
+// Returns true when the main search should not start the next iteration. Called after every completed one.
+func (threads *SearchThreads) isSoftLimitReached(bestMove Move, score int) bool {
+	elapsed := threads.since(time.Unix(0, threads.clockStartTime.Load()))
+	// time planned for the move does not start ticking until ponderhit
+	return threads.timeManager.isSoftLimitReached(bestMove, score, elapsed) && !threads.pondering.Load()
+}
+
+// Returns true when search ran out of time or nodes.
+func (threads *SearchThreads) isLimitReached() bool {
+	if threads.limits.maxNodes > 0 && threads.evaluatedNodes() >= threads.limits.maxNodes {
+		return true
+	}
+	return threads.clock.Now().UnixNano() > threads.endTime.Load()
+}
+
+// Publishes the result of best - the search returned by search().
+func (threads *SearchThreads) publishBestMove(best *Search, startTime time.Time) {
+	event := BestMoveEvent{
+		SearchStats: threads.stats(startTime),
+		Depth:       best.depthCompleted,
+		SelDepth:    best.reportedSelDepth(best.depthCompleted),
+		Score:       best.bestScore,
+		PV:          slices.Clone(best.bestLine.moves),
+	}
+	if len(best.bestLine.moves) > 0 {
+		event.Move = best.bestLine.moves[0]
+	}
+	if threads.options.ponderEnabled && len(best.bestLine.moves) > 1 {
+		event.Ponder = best.bestLine.moves[1]
+	}
+	threads.publish(event)
+}
+
+// Runs all searches of the group until the limits are reached and returns the one with the best result.
+func (threads *SearchThreads) search(startTime time.Time) *Search {
+	maxDepth := threads.limits.maxDepth
+	var helpers sync.WaitGroup
+	for _, helper := range threads.searches[1:] {
+		helpers.Add(1)
+		go func(helper *Search) {
+			defer helpers.Done()
+			helper.iterativeDeepening(startTime, maxDepth)
+		}(helper)
+	}
+	mainSearch := threads.searches[0]
+	mainSearch.iterativeDeepening(startTime, maxDepth)
+	// search may end early (mate found, depth reached) but bestmove is not allowed while pondering nor before
+	// 'stop' of infinite search
+	if !threads.mayEndEarly() {
+		<-threads.released
+	}
+	// main search is done so are the helpers
+	threads.stop.Store(true)
+	helpers.Wait()
+
+	return threads.bestSearch()
+}
+
+// Stops all searches of the group. Does not wait for them to finish.
+func (threads *SearchThreads) Stop() {
+	threads.stop.Store(true)
+	threads.release()
+}
+
+// Returns the search that completed the deepest iteration. Main search wins the ties.
+func (threads *SearchThreads) bestSearch() *Search {
+	best := threads.searches[0]
+	for _, search := range threads.searches[1:] {
+		if search.depthCompleted > best.depthCompleted {
+			best = search
+		}
+	}
+	return best
+}
+
+// Returns number of nodes evaluated by all searches of the group.
+func (threads *SearchThreads) evaluatedNodes() int64 {
+	var nodes int64
+	for _, search := range threads.searches {
+		nodes += search.evaluatedNodes.Load()
+	}
+	return nodes
+}
+
+func (threads *SearchThreads) clearKillerMoves() {
+	for _, search := range threads.searches {
+		search.clearKillerMoves()
+	}
+}
+
+func (threads *SearchThreads) clearHistory() {
+	for _, search := range threads.searches {
+		search.clearHistory()
+	}
+}
+
+// Returns true when score is a mate proving that 'go mate' limit is reached.
+func (threads *SearchThreads) isMateLimitReached(score int) bool {
+	mateIn := threads.limits.mateIn
+	return mateIn > 0 && score > ScoreCloseToMate && fullMovesToMate(score) <= mateIn
+}
+
+// Removes root moves not given in 'go searchmoves'.
+func (threads *SearchThreads) filterSearchMoves(rootMoves []rankedMove) []rankedMove {
+	return slices.DeleteFunc(rootMoves, func(m rankedMove) bool {
+		return !slices.Contains(threads.limits.searchMoves, m.mov)
+	})
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+// runs single threaded search from fen with new transposition table so that results are repeatable
+func searchWithLimits(t *testing.T, fen string, limits searchLimits) *Search {
+	gen, err := NewGeneratorFromFen(fen)
+	if err != nil {
+		t.Fatalf("Could not parse FEN: %v due to: %v", fen, err)
+	}
+	threads := NewSearchThreads(1, NewTranspositionTable(hashSizeDefault))
+	threads.SetPosition(gen)
+	threads.setLimits(limits)
+	return threads.search(time.Now())
+}
+
+const startingFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func farFuture() time.Time {
+	return time.Now().Add(time.Hour)
+}
+
+func TestGoNodes(t *testing.T) {
+	const maxNodes = 20_000
+	limits := newSearchLimits(farFuture())
+	limits.maxNodes = maxNodes
+
+	first := searchWithLimits(t, startingFen, limits)
+	if nodes := first.evaluatedNodes.Load(); nodes < maxNodes || nodes > maxNodes+100 {
+		t.Fatalf("expected to stop right after %v nodes but evaluated %v", maxNodes, nodes)
+	}
+	second := searchWithLimits(t, startingFen, limits)
+	if first.evaluatedNodes.Load() != second.evaluatedNodes.Load() ||
+		first.depthCompleted != second.depthCompleted ||
+		!slices.Equal(first.bestLine.moves, second.bestLine.moves) {
+		t.Fatalf("search limited by nodes is not repeatable: %v (depth %v) vs %v (depth %v)",
+			first.bestLine, first.depthCompleted, second.bestLine, second.depthCompleted)
+	}
+}
+
+// Principal variation must reach the horizon - also when the positions on it are in transposition table
+func TestPvLength(t *testing.T) {
+	var tests = []string{
+		startingFen,
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"8/k7/3p4/p2P1p2/P2P1P2/8/8/K7 w - - 0 1",
+	}
+	for _, fen := range tests {
+		t.Run(fen, func(t *testing.T) {
+			limits := newSearchLimits(farFuture())
+			limits.maxNodes = 30_000
+
+			search := searchWithLimits(t, fen, limits)
+			if len(search.bestLine.moves) < search.depthCompleted {
+				t.Fatalf("expected principal variation of at least %v moves but was %v",
+					search.depthCompleted, search.bestLine)
+			}
+		})
+	}
+}
+
+// every search of the group stops after its share of the node limit
+func TestGoNodesThreads(t *testing.T) {
+	const maxNodes = 40_000
+	const threadsCount = 4
+	gen := NewGenerator()
+	threads := NewSearchThreads(threadsCount, NewTranspositionTable(hashSizeDefault))
+	threads.SetPosition(gen)
+	limits := newSearchLimits(farFuture())
+	limits.maxNodes = maxNodes
+	threads.setLimits(limits)
+	threads.search(time.Now())
+
+	for _, search := range threads.searches {
+		if nodes := search.evaluatedNodes.Load(); nodes > maxNodes/threadsCount+100 {
+			t.Errorf("search %v evaluated %v nodes - more than its share of %v", search.id, nodes, maxNodes)
+		}
+	}
+	if nodes := threads.evaluatedNodes(); nodes < maxNodes/threadsCount {
+		t.Errorf("expected at least the share of the main search evaluated but was %v", nodes)
+	}
+}
+
+func TestGoMate(t *testing.T) {
+	var tests = []struct {
+		fen          string
+		mateIn       int
+		expectedMove string
+	}{
+		{"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", 1, "a1a8"},
+		{"r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1", 2, "d5f6"},
+	}
+	for _, test := range tests {
+		t.Run(test.fen, func(t *testing.T) {
+			limits := newSearchLimits(farFuture())
+			limits.mateIn = test.mateIn
+			// bounds the test in case mate limit is ignored
+			limits.maxDepth = 8
+
+			search := searchWithLimits(t, test.fen, limits)
+			if fullMovesToMate(search.bestScore) != test.mateIn || search.bestLine.moves[0].String() != test.expectedMove {
+				t.Fatalf("expected mate in %v starting with %v but was %v %v",
+					test.mateIn, test.expectedMove, formatScore(search.bestScore), search.bestLine)
+			}
+			// mate in N is proven at depth 2N-1. One more iteration at most
+			if search.depthCompleted > 2*test.mateIn {
+				t.Fatalf("search did not stop after proving mate. Depth reached: %v", search.depthCompleted)
+			}
+		})
+	}
+}
+
+func TestGoSearchMoves(t *testing.T) {
+	var tests = []struct {
+		fen         string
+		searchMoves []string
+	}{
+		{startingFen, []string{"a2a3"}},
+		{startingFen, []string{"a2a3", "h2h3", "b1a3"}},
+		// winning move Ra8# not allowed
+		{"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", []string{"g1f1", "h2h4"}},
+	}
+	for _, test := range tests {
+		t.Run(test.fen, func(t *testing.T) {
+			engine := NewEngine(io.Discard)
+			engine.ParseInputLine("position fen " + test.fen)
+			limits := newSearchLimits(farFuture())
+			limits.maxDepth = 4
+			limits.searchMoves = engine.parseSearchMoves(append(test.searchMoves, uDepth, "4"))
+			if len(limits.searchMoves) != len(test.searchMoves) {
+				t.Fatalf("expected %v but parsed %v", test.searchMoves, limits.searchMoves)
+			}
+
+			search := searchWithLimits(t, test.fen, limits)
+			if !slices.Contains(test.searchMoves, search.bestLine.moves[0].String()) {
+				t.Fatalf("expected one of %v but was %v", test.searchMoves, search.bestLine.moves[0])
+			}
+			// single move to search is not the only legal one
+			if search.depthCompleted != limits.maxDepth {
+				t.Fatalf("expected search to depth %v but completed %v", limits.maxDepth, search.depthCompleted)
+			}
+		})
+	}
+}
+
+func TestHistoryRanking(t *testing.T) {
+	gen := NewGenerator()
+	pos := gen.getTopPos()
+	search := NewSearch(NewSearchThreads(1, nil), 0)
+	prevMove := NewMove(E7, E5)
+	counterMove, historyMove := NewMove(G1, F3), NewMove(D2, D3)
+
+	search.updateHistory(pos, 5, counterMove, prevMove)
+	// enough cutoffs to age the history few times
+	for i := 0; i < 1000; i++ {
+		search.updateHistory(pos, MaxSearchDepth, historyMove, Move{})
+	}
+	moves := gen.GenerateMoves()
+	search.applyHistoryRanking(moves, pos, prevMove)
+	for _, m := range moves {
+		switch {
+		case m.mov == counterMove && m.ranking != rankingBonusCounterMove:
+			t.Fatalf("expected countermove %v to be ranked %v", m, rankingBonusCounterMove)
+		case m.mov == historyMove && (m.ranking <= 0 || m.ranking > rankingHistoryMax):
+			t.Fatalf("expected history move %v to be ranked within (0, %v]", m, rankingHistoryMax)
+		case m.mov != counterMove && m.mov != historyMove && m.ranking != 0:
+			t.Fatalf("expected %v to have no history", m)
+		}
+	}
+
+	search.clearHistory()
+	search.applyHistoryRanking(moves, pos, prevMove)
+	for _, m := range moves {
+		if m.ranking != 0 {
+			t.Fatalf("expected %v to have no history after clear", m)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package engine
+
+// Static Exchange Evaluation -> https://www.chessprogramming.org/Static_Exchange_Evaluation
+// Returns material balance (from the perspective of the side making mov) of the exchange on mov.to
+// that starts with mov. Both sides recapture with their least valuable attacker and may stop
+// whenever continuing would lose material. Pieces that moved to mov.to uncover sliding attackers
+// behind them (x-rays).
+func (pos *Position) see(mov Move) int {
+	// the longest exchange possible - every piece of both sides takes part
+	var gain [32]int
+	occupied := pos.board
+	to := mov.to
+
+	attackerValue := seePieceValue(occupied[mov.from] & ColorlessPiece)
+	attackerColor := occupied[mov.from] &^ ColorlessPiece
+	victim := occupied[to] & ColorlessPiece
+	if victim == NullPiece && to == pos.enPassSquare && attackerValue == MaterialPawnScore {
+		victim = Pawn
+		occupied[square(to.getFile()+file(mov.from.getRank()))] = NullPiece
+	}
+	gain[0] = seePieceValue(victim)
+	occupied[mov.from] = NullPiece
+
+	depth := 0
+	for depth < len(gain)-1 {
+		attackerColor = attackerColor ^ (WhitePieceBit | BlackPieceBit)
+		from, value := pos.leastValuableAttacker(&occupied, to, attackerColor)
+		if from == InvalidSquare {
+			break
+		}
+		depth++
+		// speculative gain if the piece captured last time is taken back
+		gain[depth] = attackerValue - gain[depth-1]
+		// the recapture can't change the result whatever follows - side to move would rather stand pat
+		if max(-gain[depth-1], gain[depth]) < 0 {
+			depth--
+			break
+		}
+		occupied[from] = NullPiece
+		attackerValue = value
+	}
+	for ; depth > 0; depth-- {
+		gain[depth-1] = -max(-gain[depth-1], gain[depth])
+	}
+	return gain[0]
+}
+
+// Returns square and value of the cheapest piece of color that attacks target on the occupied board.
+// InvalidSquare if there's none. Pieces removed from occupied have already been exchanged.
+func (pos *Position) leastValuableAttacker(occupied *[128]piece, target square, color piece) (square, int) {
+	pawns, pieces, kingSq := &pos.whitePawns, &pos.whitePieces, pos.whiteKing
+	pawnAttackFlag := WPawnAttacks
+	if color == BlackPieceBit {
+		pawns, pieces, kingSq = &pos.blackPawns, &pos.blackPieces, pos.blackKing
+		pawnAttackFlag = BPawnAttacks
+	}
+
+	for i := int8(0); i < pawns.size; i++ {
+		from := pawns.squares[i]
+		if occupied[from] != NullPiece && attackTable[moveIndex(from, target)]&pawnAttackFlag != 0 {
+			return from, MaterialPawnScore
+		}
+	}
+
+	bestFrom, bestValue := InvalidSquare, 0
+	for i := int8(0); i < pieces.size; i++ {
+		from := pieces.squares[i]
+		attacker := occupied[from] & ColorlessPiece
+		if attacker == NullPiece || from == target {
+			continue
+		}
+		moveIdx := moveIndex(from, target)
+		if attackTable[moveIdx]&byte(attacker) == 0 {
+			continue
+		}
+		value := seePieceValue(attacker)
+		if bestFrom != InvalidSquare && value >= bestValue {
+			continue
+		}
+		if attacker != Knight && !isPathClear(occupied, from, target, directionTable[moveIdx]) {
+			continue
+		}
+		bestFrom, bestValue = from, value
+	}
+	if bestFrom != InvalidSquare {
+		return bestFrom, bestValue
+	}
+
+	if occupied[kingSq] != NullPiece && attackTable[moveIndex(kingSq, target)]&KingAttacks != 0 {
+		return kingSq, seePieceValue(King)
+	}
+	return InvalidSquare, 0
+}
+
+// Returns true if there's nothing between from and to (exclusive) moving in direction.
+func isPathClear(occupied *[128]piece, from, to square, direction Direction) bool {
+	for sq := from + square(direction); sq != to; sq += square(direction) {
+		if occupied[sq] != NullPiece {
+			return false
+		}
+	}
+	return true
+}
+
+// Like pieceToScore() but the king is worth more than everything else - it can take part only in
+// the last capture of the exchange.
+func seePieceValue(p piece) int {
+	if p == King {
+		return seeKingValue
+	}
+	return pieceToScore(p)
+}
+
+const seeKingValue = 10 * MaterialQueenScore
+
+// Captures of pieces worth at least as much as the attacker can't lose material. Others are verified
+// with see() - winning or even ones are ranked by its result, losing ones are put after quiet moves.
+func (pos *Position) rankCaptureBySee(move *rankedMove) {
+	if move.mov.promoteTo != NullPiece {
+		return
+	}
+	attacker := pos.board[move.mov.from] & ColorlessPiece
+	victim := pos.board[move.mov.to] & ColorlessPiece
+	// en passant - pawn takes pawn
+	if victim == NullPiece || seePieceValue(victim) >= seePieceValue(attacker) {
+		return
+	}
+	seeScore := pos.see(move.mov)
+	if seeScore >= 0 {
+		move.ranking = rankingBonusTactical + int16(seeScore)
+	} else {
+		move.ranking = rankingLosingCapture + int16(seeScore)
+		move.flags |= mFlagLosingCapture
+	}
+}
@@ -0,0 +1,67 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSee(t *testing.T) {
+	var tests = []struct {
+		fen         string
+		move        string
+		expectedSee int
+	}{
+		// undefended pawn
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", MaterialPawnScore},
+		// pawn defended by pawn
+		{"1k6/8/3p4/4p3/8/8/8/1K2Q3 w - - 0 1", "e1e5", MaterialPawnScore - MaterialQueenScore},
+		// knight takes pawn defended by pawn and bishop, queen x-rays from behind
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", MaterialPawnScore - MaterialKnightScore},
+		// doubled rooks win the pawn defended by one rook
+		{"1k2r3/8/8/4p3/8/8/4R3/1K2R3 w - - 0 1", "e2e5", MaterialPawnScore},
+		// single rook against the same defence
+		{"1k2r3/8/8/4p3/8/8/8/1K2R3 w - - 0 1", "e1e5", MaterialPawnScore - MaterialRookScore},
+		// bishop behind the queen on the diagonal backs up the capture
+		{"1k6/8/5n2/8/3b4/8/1Q6/B2K4 w - - 0 1", "b2d4", MaterialBishopScore},
+		// king can't take back the pawn defended by pawn
+		{"8/8/8/4k3/3p4/2P1P3/8/4K3 w - - 0 1", "e3d4", MaterialPawnScore},
+		// ...but takes back the undefended one
+		{"8/8/8/4k3/3p4/4P3/8/4K3 w - - 0 1", "e3d4", 0},
+		// king takes undefended pawn
+		{"8/8/2p5/3p4/4k3/4P3/8/4K3 b - - 0 1", "e4e3", MaterialPawnScore},
+		// en passant
+		{"1k6/8/8/3pP3/8/8/8/1K6 w - d6 0 1", "e5d6", MaterialPawnScore},
+	}
+	for _, test := range tests {
+		t.Run(test.fen, func(t *testing.T) {
+			gen, err := NewGeneratorFromFen(test.fen)
+			if err != nil {
+				t.Fatalf("Could not parse FEN: %v due to: %v", test.fen, err)
+			}
+			idx := slices.IndexFunc(gen.GenerateMoves(), func(m rankedMove) bool {
+				return m.mov.String() == test.move
+			})
+			if idx < 0 {
+				t.Fatalf("%v is not a legal move", test.move)
+			}
+			move := gen.GenerateMoves()[idx].mov
+			if see := gen.getTopPos().see(move); see != test.expectedSee {
+				t.Fatalf("expected see(%v)=%v but was %v", test.move, test.expectedSee, see)
+			}
+		})
+	}
+}
+
+func TestPvLosingCaptureOrderedFirst(t *testing.T) {
+	gen, err := NewGeneratorFromFen("1k6/8/3p4/4p3/8/8/8/1K2Q3 w - - 0 1")
+	if err != nil {
+		t.Fatalf("Could not parse FEN: %v", err)
+	}
+	pvMove := NewMove(E1, E5)
+	moves := gen.GenerateMoves()
+	applyPvMoveBonus(moves, &Line{moves: []Move{pvMove}}, 0)
+	sortMoves(moves)
+	if moves[0].mov != pvMove {
+		t.Fatalf("expected losing capture %v from PV ordered first but was %v", pvMove, moves[0].mov)
+	}
+}
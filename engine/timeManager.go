@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"time"
+)
+
+// Time management -> https://www.chessprogramming.org/Time_Management
+// Time for the move is planned with two limits:
+//   - soft - no new iteration of iterative deepening is started past it. Iterations grow a few times longer
+//     with every ply so one started late would be aborted anyway and its time wasted.
+//   - hard - search is aborted even in the middle of an iteration (see SearchThreads.isLimitReached()).
+//
+// Soft limit is a percentage of the optimum time that is adjusted after every iteration. It grows when
+// the best move changes or the score drops (search is unsure) and shrinks while the same move stays best.
+type timeManager struct {
+	// planned time for the move
+	optimum time.Duration
+	// never exceeded
+	maximum time.Duration
+	// false when the whole time must be used (movetime, infinite). Soft limit is not checked then
+	flexible bool
+
+	softLimitPercent int
+	// best move and score of the last completed iteration. Move{} before the first one
+	bestMove Move
+	score    int
+}
+
+// Plans time for the move from the time left on the clock, increment and number of full moves to the
+// next time control. Increment is added to the clock only after the move is made so it can't be spent
+// ahead. Unless the move is the last before the time control, no more than timeHardLimitClockPercent
+// of the clock is spent on a single move. overheadMillis is kept on the clock for the delays outside of search.
+func newTimeManager(millisLeft, millisInc, movesToGo, overheadMillis int) timeManager {
+	safeMillisLeft := max(millisLeft-overheadMillis, 1)
+	optimumMillis := millisLeft/max(movesToGo, 1) + millisInc
+	maximumMillis := min(optimumMillis*timeHardLimitFactor, safeMillisLeft)
+	if movesToGo != 1 {
+		maximumMillis = min(maximumMillis, max(safeMillisLeft*timeHardLimitClockPercent/100, 1))
+	}
+	optimumMillis = min(optimumMillis, maximumMillis)
+	return timeManager{
+		optimum:          time.Duration(optimumMillis) * time.Millisecond,
+		maximum:          time.Duration(maximumMillis) * time.Millisecond,
+		flexible:         true,
+		softLimitPercent: timeSoftLimitPercent,
+	}
+}
+
+// Uses the whole moveTime
+func newFixedTimeManager(moveTime time.Duration) timeManager {
+	return timeManager{optimum: moveTime, maximum: moveTime}
+}
+
+// Adjusts the soft limit to the result of the iteration just completed. Returns true when the next
+// iteration should not be started.
+func (tm *timeManager) isSoftLimitReached(bestMove Move, score int, elapsed time.Duration) bool {
+	if !tm.flexible {
+		return false
+	}
+	if tm.bestMove != (Move{}) {
+		if bestMove != tm.bestMove {
+			tm.softLimitPercent += timeBestMoveChangePercent
+		} else {
+			tm.softLimitPercent -= timeStableBestMovePercent
+		}
+		if score < tm.score-timeScoreDropMargin {
+			tm.softLimitPercent += timeScoreDropPercent
+		}
+		tm.softLimitPercent = min(max(tm.softLimitPercent, timeSoftLimitMinPercent), timeSoftLimitMaxPercent)
+	}
+	tm.bestMove, tm.score = bestMove, score
+	return elapsed >= tm.softLimit()
+}
+
+func (tm *timeManager) softLimit() time.Duration {
+	softLimit := tm.optimum * time.Duration(tm.softLimitPercent) / 100
+	if softLimit > tm.maximum {
+		return tm.maximum
+	}
+	return softLimit
+}
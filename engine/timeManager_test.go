@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTimeManagerBudget(t *testing.T) {
+	var tests = []struct {
+		millisLeft, millisInc, movesToGo int
+	}{
+		{60_000, 0, ExpectedFullMovesToBePlayed},
+		{60_000, 1000, ExpectedFullMovesToBePlayed},
+		{1000, 0, ExpectedFullMovesToBePlayed},
+		{300, 2000, ExpectedFullMovesToBePlayed},
+		{10_000, 0, 1},
+		{10_000, 0, 2},
+		// notes.log 25.06.2024 - exceeded time by 205 millis
+		{500, 500, 2},
+		{501, 500, 2},
+		{10, 0, 1},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%+v", test), func(t *testing.T) {
+			tm := newTimeManager(test.millisLeft, test.millisInc, test.movesToGo, moveOverheadDefault)
+			left := time.Duration(test.millisLeft) * time.Millisecond
+			if tm.maximum <= 0 || tm.optimum <= 0 || tm.optimum > tm.maximum {
+				t.Fatalf("expected 0 < optimum <= maximum but was %v, %v", tm.optimum, tm.maximum)
+			}
+			if tm.maximum > time.Duration(max(test.millisLeft-moveOverheadDefault, 1))*time.Millisecond {
+				t.Fatalf("maximum %v does not leave a margin from the clock %v", tm.maximum, left)
+			}
+			if test.movesToGo > 1 && tm.maximum > left/2 {
+				t.Fatalf("maximum %v spends more than half of the clock %v before time control", tm.maximum, left)
+			}
+		})
+	}
+}
+
+// Fake clock for the time manager: every iteration takes iterationGrowth times longer than the previous one
+// and finds bestMoves[i] with scores[i]. Returns the time when the search stopped (after the last completed
+// iteration or at the hard limit).
+func simulateIterations(tm timeManager, bestMoves []Move, scores []int) time.Duration {
+	const iterationGrowth = 3
+	var elapsed time.Duration
+	iterationTime := time.Millisecond
+	for i := 0; ; i++ {
+		if elapsed+iterationTime > tm.maximum {
+			return tm.maximum
+		}
+		elapsed += iterationTime
+		iterationTime *= iterationGrowth
+		if tm.isSoftLimitReached(bestMoves[i%len(bestMoves)], scores[i%len(scores)], elapsed) {
+			return elapsed
+		}
+	}
+}
+
+func TestTimeManagerStability(t *testing.T) {
+	moveA, moveB := NewMove(E2, E4), NewMove(D2, D4)
+	stableMove := simulateIterations(newTimeManager(60_000, 0, 20, moveOverheadDefault), []Move{moveA}, []int{10})
+	changingMove := simulateIterations(newTimeManager(60_000, 0, 20, moveOverheadDefault), []Move{moveA, moveB}, []int{10})
+	droppingScore := simulateIterations(newTimeManager(60_000, 0, 20, moveOverheadDefault), []Move{moveA},
+		[]int{300, 200, 100, 0, -100, -200, -300, -400, -500, -600, -700, -800})
+	if stableMove >= changingMove {
+		t.Errorf("expected more time for changing best move %v than for the stable one %v", changingMove, stableMove)
+	}
+	if stableMove >= droppingScore {
+		t.Errorf("expected more time for dropping score %v than for the stable one %v", droppingScore, stableMove)
+	}
+	fixed := simulateIterations(newFixedTimeManager(time.Second), []Move{moveA}, []int{10})
+	if fixed != time.Second {
+		t.Errorf("expected fixed time manager to use the whole time but used %v", fixed)
+	}
+}
@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Transposition table -> https://www.chessprogramming.org/Transposition_Table
+// Fixed size, power of two number of slots indexed by lowest bits of the zobrist hash.
+type TranspositionTable struct {
+	slots []ttSlot
+	mask  uint64
+}
+
+// Slots are read and written by all Lazy SMP searches without locking. Full hash is stored xored with
+// packed entry so that a slot torn by two concurrent writes does not match any key -
+// https://www.chessprogramming.org/Shared_Hash_Table#Lockless
+type ttSlot struct {
+	keyXorData atomic.Uint64
+	data       atomic.Uint64
+}
+
+type ttEntry struct {
+	move  Move
+	score int32
+	// remaining depth that the score was searched to. 0 for quiescence
+	depth int8
+	bound boundType
+}
+
+// tells how the score stored in ttEntry relates to the real score of the position
+type boundType byte
+
+const (
+	// empty slot
+	boundNone boundType = iota
+	// score is exact - it was within alpha-beta window
+	boundExact
+	// search failed high - real score is at least that much
+	boundLower
+	// search failed low - real score is at most that much
+	boundUpper
+)
+
+// Returns table that takes at most sizeMB megabytes.
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	var slot ttSlot
+	maxSlots := uint64(sizeMB) * 1024 * 1024 / uint64(unsafe.Sizeof(slot))
+	slotsCount := uint64(1) << (bits.Len64(maxSlots) - 1)
+	return &TranspositionTable{
+		slots: make([]ttSlot, slotsCount),
+		mask:  slotsCount - 1,
+	}
+}
+
+// Replaces the table with an empty one of sizeMB. Searches sharing the table keep using it.
+// Must not be called while search is running.
+func (tt *TranspositionTable) resize(sizeMB int) {
+	*tt = *NewTranspositionTable(sizeMB)
+}
+
+// Must not be called while search is running.
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.slots {
+		tt.slots[i].keyXorData.Store(0)
+		tt.slots[i].data.Store(0)
+	}
+}
+
+// Returns per mille of the table in use - estimated from the first slots.
+func (tt *TranspositionTable) hashfull() int {
+	sampleSize := min(1000, len(tt.slots))
+	used := 0
+	for i := 0; i < sampleSize; i++ {
+		if tt.slots[i].data.Load() != 0 {
+			used++
+		}
+	}
+	return used * 1000 / sampleSize
+}
+
+// Returns entry stored for a position with the hash key.
+func (tt *TranspositionTable) probe(key uint64) (entry ttEntry, found bool) {
+	slot := &tt.slots[key&tt.mask]
+	data := slot.data.Load()
+	if slot.keyXorData.Load()^data != key {
+		return ttEntry{}, false
+	}
+	entry = unpackTTEntry(data)
+	return entry, entry.bound != boundNone
+}
+
+// Stores search result of position with the hash key. Param plyFromRoot is needed to store mate scores
+// relative to the position rather than to the root of the search.
+func (tt *TranspositionTable) store(key uint64, remainingDepth, plyFromRoot int, bound boundType, score int, move Move) {
+	// keep deeper results of the same position. Other positions are always replaced
+	if stored, found := tt.probe(key); found && int(stored.depth) > remainingDepth && bound != boundExact {
+		return
+	}
+	data := ttEntry{
+		move:  move,
+		score: int32(scoreToTT(score, plyFromRoot)),
+		depth: int8(remainingDepth),
+		bound: bound,
+	}.pack()
+	slot := &tt.slots[key&tt.mask]
+	slot.data.Store(data)
+	slot.keyXorData.Store(key ^ data)
+}
+
+// bit layout of packed entry (lowest bits first):
+// score:32 depth:8 bound:2 from:7 to:7 promoteTo:6 doublePush:1
+func (entry ttEntry) pack() uint64 {
+	data := uint64(uint32(entry.score)) |
+		uint64(uint8(entry.depth))<<32 |
+		uint64(entry.bound)<<40 |
+		uint64(entry.move.from)<<42 |
+		uint64(entry.move.to)<<49 |
+		uint64(entry.move.promoteTo&ColorlessPiece)<<56
+	if entry.move.enPassant != InvalidSquare {
+		data |= 1 << 62
+	}
+	return data
+}
+
+func unpackTTEntry(data uint64) ttEntry {
+	move := Move{
+		from:      square(data >> 42 & 0x7F),
+		to:        square(data >> 49 & 0x7F),
+		promoteTo: piece(data >> 56 & 0x3F),
+		enPassant: InvalidSquare,
+	}
+	// only double pushes have en passant square set - see generatePseudoLegalMoves()
+	if data&(1<<62) != 0 {
+		move.enPassant = (move.from + move.to) / 2
+	}
+	return ttEntry{
+		move:  move,
+		score: int32(uint32(data)),
+		depth: int8(uint8(data >> 32)),
+		bound: boundType(data >> 40 & 0x3),
+	}
+}
+
+// Returns score that can be returned from the search instead of searching the position again.
+// The returned score is clamped to alpha-beta window just like alphaBeta() does (fail-hard).
+func (entry *ttEntry) cutoffScore(alpha, beta, plyFromRoot int) (score int, ok bool) {
+	score = scoreFromTT(int(entry.score), plyFromRoot)
+	switch entry.bound {
+	case boundExact:
+		return min(max(score, alpha), beta), true
+	case boundLower:
+		if score >= beta {
+			return beta, true
+		}
+	case boundUpper:
+		if score <= alpha {
+			return alpha, true
+		}
+	}
+	return 0, false
+}
+
+// Mate scores are given relative to the root (see terminalNodeScore()). The same position can appear
+// at different plies so in the table mate scores are stored as distance from the position itself.
+func scoreToTT(score, plyFromRoot int) int {
+	if score > ScoreCloseToMate {
+		return score + plyFromRoot
+	} else if score < -ScoreCloseToMate {
+		return score - plyFromRoot
+	}
+	return score
+}
+
+func scoreFromTT(score, plyFromRoot int) int {
+	if score > ScoreCloseToMate {
+		return score - plyFromRoot
+	} else if score < -ScoreCloseToMate {
+		return score + plyFromRoot
+	}
+	return score
+}
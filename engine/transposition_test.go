@@ -0,0 +1,64 @@
+package engine
+
+import "testing"
+
+func TestTTEntryPackRoundTrip(t *testing.T) {
+	var tests = []ttEntry{
+		{Move{}, 0, 0, boundExact},
+		{NewMove(E2, E3), -1, 1, boundLower},
+		{Move{E2, E4, NullPiece, E3}, 1234, 7, boundUpper},
+		{Move{D7, D5, NullPiece, D6}, -LostScore - 7, MaxSearchDepth - 1, boundExact},
+		{NewMove(E5, D6), LostScore + 7, -1, boundLower},
+		{NewPromotionMove(A7, A8, Queen), InfinityScore, 3, boundUpper},
+		{NewPromotionMove(H2, G1, Knight), MinusInfinityScore, 12, boundExact},
+		{NewMove(H8, A1), 0, 0, boundLower},
+	}
+	for _, entry := range tests {
+		if unpacked := unpackTTEntry(entry.pack()); unpacked != entry {
+			t.Errorf("expected %+v but was %+v", entry, unpacked)
+		}
+	}
+}
+
+func TestTTStoreAndProbe(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	gen := NewGenerator()
+	key := gen.getTopPos().hash
+	move := gen.GenerateMoves()[0].mov
+
+	tt.store(key, 5, 0, boundExact, 42, move)
+	entry, found := tt.probe(key)
+	if !found || entry.move != move || entry.score != 42 || entry.depth != 5 || entry.bound != boundExact {
+		t.Fatalf("unexpected entry %+v found:%v", entry, found)
+	}
+	// shallower non-exact result must not replace deeper one
+	tt.store(key, 2, 0, boundLower, 50, Move{})
+	if entry, _ = tt.probe(key); entry.depth != 5 {
+		t.Fatalf("deeper entry replaced by %+v", entry)
+	}
+	if _, found = tt.probe(key ^ 1<<63); found {
+		t.Fatalf("found entry for a different key")
+	}
+	tt.Clear()
+	if _, found = tt.probe(key); found {
+		t.Fatalf("found entry in cleared table")
+	}
+}
+
+func TestHashfull(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	if full := tt.hashfull(); full != 0 {
+		t.Fatalf("expected empty table but was %v", full)
+	}
+	// every other slot of the sample
+	for key := uint64(0); key < 1000; key += 2 {
+		tt.store(key, 1, 0, boundExact, 0, Move{})
+	}
+	if full := tt.hashfull(); full != 500 {
+		t.Fatalf("expected half full table but was %v", full)
+	}
+	tt.Clear()
+	if full := tt.hashfull(); full != 0 {
+		t.Fatalf("expected empty table after clear but was %v", full)
+	}
+}
@@ -2,301 +2,544 @@ package engine
 
 import (
 	"fmt"
+	"io"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	uUci      string = "uci"
-	uIsReady  string = "isready"
+	uUci        string = "uci"
+	uIsReady    string = "isready"
+	uUciNewGame string = "ucinewgame"
 	uPosition string = "position"
 	uStartpos string = "startpos"
+	uFen      string = "fen"
 	uMoves    string = "moves"
 
-	uGo        string = "go"
-	uWtime     string = "wtime"
-	uBtime     string = "btime"
-	uWinc      string = "winc"
-	uBinc      string = "binc"
-	uMovesToGo string = "movestogo"
-	uDepth     string = "depth"
-	uInfinite  string = "infinite"
-	uMoveTime  string = "movetime"
+	uGo          string = "go"
+	uWtime       string = "wtime"
+	uBtime       string = "btime"
+	uWinc        string = "winc"
+	uBinc        string = "binc"
+	uMovesToGo   string = "movestogo"
+	uDepth       string = "depth"
+	uInfinite    string = "infinite"
+	uMoveTime    string = "movetime"
+	uPonder      string = "ponder"
+	uNodes       string = "nodes"
+	uMate        string = "mate"
+	uSearchMoves string = "searchmoves"
+
+	uPonderHit string = "ponderhit"
 
 	uOptionSet   string = "setoption"
 	uOptionName  string = "name"
 	uOptionValue string = "value"
+
+	uDebug    string = "debug"
+	uOn       string = "on"
+	uOff      string = "off"
+	uRegister string = "register"
 )
 
-// anti 'loose on time' duration in case of delays (printing on console, GC kicking in, system clock granularity)
-const antiflagMillis int = 50
+// parameters of 'go' - they end the list of searchmoves
+var goParams = []string{uWtime, uBtime, uWinc, uBinc, uMovesToGo, uDepth, uInfinite, uMoveTime, uPonder, uNodes,
+	uMate, uSearchMoves}
+
+// time left on the clock when it's not given. Makes search run for few years - good enough.
+const infiniteMillis int = 100_000_000_000
+
+// Executes single command given in UCI protocol (or one of the non-UCI commands listed in 'help'). 'xboard'
+// switches the engine to XBoard protocol for the rest of its life. Commands received while searching are
+// queued and executed when the search is done - all but the ones that control the search. 'quit' stops the
+// search and returns once it's done.
+func (engine *Engine) ParseInputLine(inputLine string) {
+	engine.commandMutex.Lock()
+	if engine.searchRunning && !engine.canExecuteWhileSearching(inputLine, engine.pendingCommands) {
+		engine.pendingCommands = append(engine.pendingCommands, inputLine)
+		if engine.xboard != nil && interruptsXboardSearch(inputLine) {
+			engine.xboard.discardMove = true
+			engine.searchThreads.Stop()
+		}
+	} else {
+		engine.executeCommand(inputLine)
+	}
+	quit := engine.quit
+	engine.commandMutex.Unlock()
+	if quit {
+		engine.Wait()
+	}
+}
+
+// Returns true for commands executed at once even though the search is running - according to the rules of
+// the protocol in use.
+func (engine *Engine) canExecuteWhileSearching(inputLine string, queuedBefore []string) bool {
+	if engine.xboard != nil {
+		return isXboardExecutableWhileSearching(inputLine)
+	}
+	return isExecutableWhileSearching(inputLine, queuedBefore)
+}
+
+// Returns true for UCI commands that don't touch anything the running search uses. 'stop' and 'ponderhit' queued
+// after 'go' are meant for the search it starts so they wait in the queue too.
+func isExecutableWhileSearching(inputLine string, queuedBefore []string) bool {
+	switch {
+	case inputLine == uIsReady || inputLine == "quit" || strings.HasPrefix(inputLine, uDebug):
+		return true
+	case inputLine == "stop" || inputLine == uPonderHit:
+		return !slices.ContainsFunc(queuedBefore, func(command string) bool {
+			return strings.HasPrefix(command, uGo)
+		})
+	}
+	return false
+}
 
-var posGen *Generator
-var search *Search
-var Quit bool
+// Executes commands queued while searching until one of them has to wait for the search it started.
+// Must be called with commandMutex held.
+func (engine *Engine) executePendingCommands() {
+	for len(engine.pendingCommands) > 0 && !engine.quit {
+		inputLine := engine.pendingCommands[0]
+		if engine.searchRunning && !engine.canExecuteWhileSearching(inputLine, nil) {
+			return
+		}
+		engine.pendingCommands = engine.pendingCommands[1:]
+		engine.executeCommand(inputLine)
+	}
+}
 
-func ParseInputLine(inputLine string) {
+func (engine *Engine) executeCommand(inputLine string) {
+	if engine.xboard != nil {
+		engine.executeXboardCommand(inputLine)
+		return
+	}
 	if inputLine == uIsReady {
-		search = NewSearch()
-		fmt.Println("readyok")
+		// answered at once - also while searching. Commands that can't run meanwhile are queued anyway
+		fmt.Fprintln(engine.out, "readyok")
+	} else if inputLine == uUciNewGame {
+		engine.doUciNewGame()
+	} else if strings.HasPrefix(inputLine, uDebug) {
+		engine.doDebug(strings.TrimSpace(strings.TrimPrefix(inputLine, uDebug)))
+	} else if strings.HasPrefix(inputLine, uRegister) {
+		// engine is free - registration is never asked for nor checked
+		engine.debugInfo("registration not required")
 	} else if inputLine == "eval" {
-		fmt.Println(Evaluate(posGen.getTopPos(), 0, true))
+		engine.doEval()
 	} else if inputLine == "quit" {
-		Quit = true
+		engine.quit = true
+		engine.searchThreads.Stop()
 	} else if strings.HasPrefix(inputLine, uPosition) {
-		doPosition(strings.TrimSpace(strings.TrimPrefix(inputLine, uPosition)))
+		engine.doPosition(strings.TrimSpace(strings.TrimPrefix(inputLine, uPosition)))
 	} else if inputLine == uUci {
-		doUci()
+		engine.doUci()
+	} else if inputLine == xXboard {
+		engine.startXboard()
 	} else if strings.HasPrefix(inputLine, uGo) {
-		doGo(strings.TrimSpace(strings.TrimPrefix(inputLine, uGo)))
+		engine.doGo(strings.TrimSpace(strings.TrimPrefix(inputLine, uGo)))
+	} else if inputLine == uPonderHit {
+		engine.searchThreads.PonderHit(engine.searchThreads.clock.Now())
 	} else if inputLine == "stop" {
-		if search != nil && !search.interrupted {
-			search.stop <- true
-		}
+		engine.searchThreads.Stop()
 	} else if strings.HasPrefix(inputLine, uOptionSet) {
-		setOption(strings.TrimSpace(strings.TrimPrefix(inputLine, uOptionSet)))
+		engine.setOption(strings.TrimSpace(strings.TrimPrefix(inputLine, uOptionSet)))
 		// non-uci commands
 	} else if inputLine == "tostr" {
-		fmt.Printf("%v\n", posGen)
+		fmt.Fprintf(engine.out, "%v\n", engine.posGen)
+	} else if inputLine == uFen {
+		engine.doFen()
 	} else if strings.HasPrefix(inputLine, "perft") {
-		doPerftDivide(strings.TrimSpace(strings.TrimPrefix(inputLine, "perft")))
+		engine.doPerftDivide(strings.TrimSpace(strings.TrimPrefix(inputLine, "perft")))
 	} else if strings.HasPrefix(inputLine, "tperft") {
-		doTacticalPerftDivide(strings.TrimSpace(strings.TrimPrefix(inputLine, "tperft")))
+		engine.doTacticalPerftDivide(strings.TrimSpace(strings.TrimPrefix(inputLine, "tperft")))
 	} else if inputLine == "help" {
-		printHelp()
+		printHelp(engine.out)
 	}
 }
 
-func printHelp() {
-	fmt.Println(`Available UCI commands:
+func printHelp(out io.Writer) {
+	fmt.Fprintln(out, `Available UCI commands:
  * uci - print engine info and options
  * isready - print 'readyok' when the engine is ready
+ * ucinewgame - forget everything learned while searching previous positions and the game played
+ * debug [on | off] - turn diagnostics printed as 'info string' on/off
  * setoption name <name> value <value> - set an UCI option
  * position [startpos | fen <fenstring> [moves <move1> ... <movei>]] - set position
- * go [depth <depth> | movetime <time> | wtime <time> | btime <time> | winc <time> | binc <time> | movestogo <moves> | infinite] - start search
+ * go [depth <depth> | nodes <nodes> | mate <moves> | movetime <time> | wtime <time> | btime <time> | winc <time> | binc <time> | movestogo <moves> | infinite | ponder | searchmoves <move1> ... <movei>] - start search
  * stop - stop searching
+ * ponderhit - the opponent played the move pondered on, keep searching on own time
  * quit - quit the engine
+ * xboard - switch to XBoard protocol (CECP)
 Other available commands:
  * perft <depth> - count number of moves possible from current position
  * tperft <depth> - same as perft but at <depth> count only captures and promotions. Useful for testing movegen in quiescence search.
  * tostr - print current position
+ * fen - print FEN of current position
  * eval - evaluate current position`)
 }
 
-func doPerftDivide(perftArg string) {
+func (engine *Engine) doPerftDivide(perftArg string) {
 	depth, err := strconv.Atoi(perftArg)
 	if err != nil || depth <= 0 {
-		fmt.Println("Invalid depth: ", perftArg)
+		fmt.Fprintln(engine.out, "Invalid depth: ", perftArg)
 		return
 	}
-	if posGen == nil {
-		fmt.Println("No position set to count perft from")
+	if engine.posGen == nil {
+		fmt.Fprintln(engine.out, "No position set to count perft from")
 		return
 	}
-	posGen.Perftd(depth)
+	engine.posGen.Perftd(depth, engine.out)
 }
 
-func doTacticalPerftDivide(tperftArg string) {
+func (engine *Engine) doTacticalPerftDivide(tperftArg string) {
 	depth, err := strconv.Atoi(tperftArg)
 	if err != nil || depth <= 0 {
-		fmt.Println("Invalid depth: ", tperftArg)
+		fmt.Fprintln(engine.out, "Invalid depth: ", tperftArg)
 		return
 	}
-	if posGen == nil {
-		fmt.Println("No position set to count perft from")
+	if engine.posGen == nil {
+		fmt.Fprintln(engine.out, "No position set to count perft from")
 		return
 	}
-	posGen.PerftDivTactical(depth)
+	engine.posGen.PerftDivTactical(depth, engine.out)
 }
 
-func setOption(setOptionCommand string) {
-	// So far this func can only set one option. Will upgrade it if needed.
+func (engine *Engine) doFen() {
+	if engine.posGen == nil {
+		fmt.Fprintln(engine.out, "No position set to print FEN of")
+		return
+	}
+	fmt.Fprintln(engine.out, engine.posGen.getTopPos().Fen())
+}
 
-	tokens := strings.Split(setOptionCommand, " ")
-	if len(tokens) != 4 {
+func (engine *Engine) doEval() {
+	if engine.posGen == nil {
+		fmt.Fprintln(engine.out, "No position set to evaluate")
 		return
 	}
+	fmt.Fprintln(engine.out, Evaluate(engine.posGen.getTopPos(), 0, engine.out))
+}
+
+// Forgets the game played so far and everything learned while searching its positions.
+func (engine *Engine) doUciNewGame() {
+	engine.transpositionTable.Clear()
+	engine.searchThreads.clearHistory()
+	engine.searchThreads.clearKillerMoves()
+	engine.posGen = nil
+}
+
+func (engine *Engine) doDebug(debugArg string) {
+	switch debugArg {
+	case uOn, "":
+		engine.debug = true
+	case uOff:
+		engine.debug = false
+	default:
+		fmt.Fprintln(engine.out, "info string invalid debug command:", debugArg)
+	}
+}
+
+// Prints a as 'info string' when debug mode is on.
+func (engine *Engine) debugInfo(a ...any) {
+	if engine.debug {
+		fmt.Fprintln(engine.out, append([]any{"info string"}, a...)...)
+	}
+}
 
-	if tokens[0] != uOptionName || tokens[2] != uOptionValue {
+// Applies 'setoption name <id> [value <x>]'. Names may contain spaces (e.g. "Clear Hash") and buttons come
+// without value. Invalid commands are reported with 'info string'.
+func (engine *Engine) setOption(setOptionCommand string) {
+	if !strings.HasPrefix(setOptionCommand, uOptionName+" ") {
+		fmt.Fprintln(engine.out, "info string invalid setoption command:", setOptionCommand)
 		return
 	}
-	if tokens[1] == currmoveLogIntervalKey {
-		val, err := strconv.Atoi(tokens[3])
-		if err == nil {
-			currmoveLogInterval = val
-		}
+	nameAndValue := strings.TrimPrefix(setOptionCommand, uOptionName+" ")
+	name, value, _ := strings.Cut(nameAndValue, " "+uOptionValue+" ")
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+
+	option := findOption(uciOptions, name)
+	if option == nil {
+		fmt.Fprintln(engine.out, "info string unknown option:", name)
+		return
+	}
+	if err := option.set(engine, value); err != nil {
+		fmt.Fprintf(engine.out, "info string option %v: %v\n", option.name(), err)
 	}
 }
 
-func doUci() {
-	fmt.Println("id name Magog " + VERSION_STRING)
-	fmt.Println("id author Maciej Smolczewski")
-	fmt.Println("option",
-		uOptionName, currmoveLogIntervalKey,
-		"type", "spin",
-		"default", currmoveLogIntervalDefault,
-		"min", currmoveLogIntervalMin,
-		"max", currmoveLogIntervalMax,
-	)
-	fmt.Println("uciok")
+func (engine *Engine) doUci() {
+	fmt.Fprintln(engine.out, "id name Magog " + VERSION_STRING)
+	fmt.Fprintln(engine.out, "id author Maciej Smolczewski")
+	for _, option := range uciOptions {
+		fmt.Fprintln(engine.out, "option", uOptionName, option.name(), option.declaration())
+	}
+	fmt.Fprintln(engine.out, "uciok")
 }
 
-func doPosition(positionCommand string) {
+func (engine *Engine) doPosition(positionCommand string) {
 	movesIdx := strings.Index(positionCommand, uMoves)
 	if movesIdx == -1 {
-		parsePosition(positionCommand)
+		engine.parsePosition(positionCommand)
 	} else {
-		parsePosition(strings.TrimSpace(positionCommand[:movesIdx]))
+		engine.parsePosition(strings.TrimSpace(positionCommand[:movesIdx]))
 
 		movesString := strings.TrimSpace(positionCommand[movesIdx+len(uMoves):])
 		moveStrings := strings.Split(movesString, " ")
 		for _, moveStr := range moveStrings {
 			move, err := parseMoveString(moveStr)
 			if err != nil {
-				fmt.Println("Invalid position command:", err)
+				fmt.Fprintln(engine.out, "Invalid position command:", err)
 				return
 			}
-			posGen.ApplyUciMove(move)
+			engine.posGen.ApplyUciMove(move)
 		}
 	}
-	//clear killer moves
-	for _, killers := range killerMoves {
-		killers[0] = Move{}
-		killers[1] = Move{}
-	}
+	engine.searchThreads.clearKillerMoves()
 }
 
-func doGo(goCommand string) {
-	startTime := time.Now()
-	if posGen == nil {
-		fmt.Println("No position set to start search from")
+func (engine *Engine) doGo(goCommand string) {
+	if engine.posGen == nil {
+		fmt.Fprintln(engine.out, "No position set to start search from")
 		return
 	}
-	if search == nil {
-		search = NewSearch()
+	threads := engine.searchThreads
+	startTime := threads.clock.Now()
+	limits, ok := engine.parseGoLimits(goCommand, startTime)
+	if !ok {
+		fmt.Fprintln(engine.out, "info string invalid go command:", goCommand)
+		return
 	}
+	if engine.debug {
+		var breakdown strings.Builder
+		score := Evaluate(engine.posGen.getTopPos(), 0, &breakdown)
+		engine.debugInfo("static eval", score, strings.TrimSpace(breakdown.String()))
+		engine.debugInfo("time optimum", limits.timeManager.optimum.Milliseconds(),
+			"maximum", limits.timeManager.maximum.Milliseconds(),
+			"flexible", limits.timeManager.flexible)
+	}
+	engine.startSearch(limits, nil)
+}
 
+// Returns limits of the search from 'go' command for the position set in engine. Not ok if the command is malformed.
+func (engine *Engine) parseGoLimits(goCommand string, startTime time.Time) (limits searchLimits, ok bool) {
 	tokens := strings.Split(goCommand, " ")
 	// if specified - search exactly this numer of millis
 	moveTimeMillis := -1
-	blackMillisLeft := 100_000_000_000
-	whiteMillisLeft := 100_000_000_000
+	blackMillisLeft := infiniteMillis
+	whiteMillisLeft := infiniteMillis
 	blackMillisIncrement := 0
 	whiteMillisIncrement := 0
 	fullMovesToGo := ExpectedFullMovesToBePlayed
-	targetDepth := MaxSearchDepth
+	limits = newSearchLimits(time.Time{})
 
 	var err error
 
-out:
 	for i, token := range tokens {
 		switch token {
 		case uMoveTime:
-			moveTimeMillis, err = strconv.Atoi(tokens[i+1])
+			// takes precedence over the clock params
+			moveTimeMillis, err = parseGoParamValue(tokens, i)
 			if err != nil {
-				return
+				return limits, false
 			}
-			//ignore rest of params
-			break out
 		case uInfinite:
 			// Default value of ****MillisLeft should make it search  for few years - good enough.
-			// Ignore rest of params
-			break out
+			// Clock params are ignored
+			limits.infinite = true
 		case uWtime:
-			whiteMillisLeft, err = strconv.Atoi(tokens[i+1])
+			whiteMillisLeft, err = parseGoParamValue(tokens, i)
 			if err != nil {
-				return
+				return limits, false
 			}
 		case uBtime:
-			blackMillisLeft, err = strconv.Atoi(tokens[i+1])
+			blackMillisLeft, err = parseGoParamValue(tokens, i)
 			if err != nil {
-				return
+				return limits, false
 			}
 		case uWinc:
-			whiteMillisIncrement, err = strconv.Atoi(tokens[i+1])
+			whiteMillisIncrement, err = parseGoParamValue(tokens, i)
 			if err != nil {
-				return
+				return limits, false
 			}
 		case uBinc:
-			blackMillisIncrement, err = strconv.Atoi(tokens[i+1])
+			blackMillisIncrement, err = parseGoParamValue(tokens, i)
 			if err != nil {
-				return
+				return limits, false
 			}
 		case uMovesToGo:
-			fullMovesToGo, err = strconv.Atoi(tokens[i+1])
+			fullMovesToGo, err = parseGoParamValue(tokens, i)
 			if err != nil {
-				return
+				return limits, false
 			}
+		case uPonder:
+			limits.pondering = true
 		case uDepth:
-			targetDepth, err = strconv.Atoi(tokens[i+1])
-			if err != nil || targetDepth < 1 {
-				return
+			limits.maxDepth, err = parseGoParamValue(tokens, i)
+			if err != nil || limits.maxDepth < 1 {
+				return limits, false
 			}
+		case uNodes:
+			var maxNodes int
+			maxNodes, err = parseGoParamValue(tokens, i)
+			if err != nil || maxNodes < 1 {
+				return limits, false
+			}
+			limits.maxNodes = int64(maxNodes)
+		case uMate:
+			limits.mateIn, err = parseGoParamValue(tokens, i)
+			if err != nil || limits.mateIn < 1 {
+				return limits, false
+			}
+		case uSearchMoves:
+			limits.searchMoves = engine.parseSearchMoves(tokens[i+1:])
 		}
 	}
-	var endtime time.Time
-	if moveTimeMillis != -1 {
-		moveTimeMillis -= antiflagMillis
-		endtime = startTime.Add(time.Duration(moveTimeMillis * int(time.Millisecond)))
+	if limits.infinite {
+		limits.timeManager = newFixedTimeManager(time.Duration(infiniteMillis) * time.Millisecond)
+	} else if moveTimeMillis != -1 {
+		moveTimeMillis = max(moveTimeMillis-engine.options.moveOverhead, 1)
+		limits.timeManager = newFixedTimeManager(time.Duration(moveTimeMillis) * time.Millisecond)
+	} else if engine.posGen.getTopPos().flags&FlagWhiteTurn == 0 {
+		limits.timeManager = newTimeManager(blackMillisLeft, blackMillisIncrement, fullMovesToGo, engine.options.moveOverhead)
 	} else {
-		endtime = calcEndtime(startTime, blackMillisLeft, blackMillisIncrement, whiteMillisLeft, whiteMillisIncrement,
-			fullMovesToGo)
+		limits.timeManager = newTimeManager(whiteMillisLeft, whiteMillisIncrement, fullMovesToGo, engine.options.moveOverhead)
+	}
+	limits.startTime = startTime
+	limits.endTime = startTime.Add(limits.timeManager.maximum)
+	if limits.pondering {
+		// search the expected position on opponent's time - until ponderhit or stop
+		limits.endTime = startTime.Add(time.Duration(infiniteMillis) * time.Millisecond)
 	}
-	go search.StartIterativeDeepening(startTime, endtime, targetDepth)
+	return limits, true
 }
 
-func calcEndtime(startTime time.Time, blackMillisLeft, blackMillisInc, whiteMillisLeft, whiteMillisInc int,
-	movesToGo int) time.Time {
-	isBlackTurn := posGen.getTopPos().flags&FlagWhiteTurn == 0
-	var millisForMove int
-	if isBlackTurn {
-		if blackMillisLeft > blackMillisInc {
-			millisForMove = min(blackMillisLeft/movesToGo + blackMillisInc, blackMillisLeft)
-		} else {
-			millisForMove = blackMillisLeft
+// Returns integer value given after the parameter at tokens[i].
+func parseGoParamValue(tokens []string, i int) (int, error) {
+	if i+1 >= len(tokens) {
+		return 0, fmt.Errorf("missing value of %v", tokens[i])
+	}
+	return strconv.Atoi(tokens[i+1])
+}
+
+// Returns legal moves listed at the beginning of tokens - up to the next parameter of 'go'. Tokens that are
+// not legal moves are reported with 'info string' and skipped.
+func (engine *Engine) parseSearchMoves(tokens []string) []Move {
+	var searchMoves []Move
+	for _, token := range tokens {
+		if slices.Contains(goParams, token) {
+			break
 		}
-	} else {
-		if whiteMillisLeft > whiteMillisInc {
-			millisForMove = min(whiteMillisLeft/movesToGo + whiteMillisInc, whiteMillisLeft)
+		move, err := parseMoveString(token)
+		if err != nil {
+			fmt.Fprintln(engine.out, "info string invalid searchmoves move:", token)
+			continue
+		}
+		if legal, ok := engine.posGen.findLegalMove(move); ok {
+			searchMoves = append(searchMoves, legal)
 		} else {
-			millisForMove = whiteMillisLeft
+			fmt.Fprintln(engine.out, "info string illegal searchmoves move:", token)
 		}
 	}
-	millisForMove -= antiflagMillis
-	millisForMove = max(millisForMove, 1)
+	return searchMoves
+}
 
-	endtime := startTime.Add(time.Millisecond * time.Duration(millisForMove))
-	return endtime
+// Front-end of the search that prints its events as UCI info and bestmove lines to out.
+type uciListener struct {
+	out io.Writer
+	// UCI_ShowWDL option
+	showWDL bool
 }
 
-func maybePrintNewPvInfo(score, depth int, bestLine []Move, timeElapsed time.Duration, debugSuffix string) {
-	if timeElapsed < time.Duration(200*time.Millisecond) {
+func (listener *uciListener) OnSearchEvent(event SearchEvent) {
+	switch event := event.(type) {
+	case IterationEvent:
+		listener.printInfoAfterDepth(event)
+	case PvEvent:
+		if event.Bound == ExactScore {
+			listener.maybePrintNewPvInfo(event)
+		} else {
+			listener.printBoundInfo(event)
+		}
+	case CurrMoveEvent:
+		fmt.Fprintln(listener.out, "info depth", event.Depth,
+			"currmove", event.Move,
+			"currmovenumber", event.Number,
+			"nodes", event.Nodes,
+			"time", event.Time.Milliseconds(),
+			"nps", nps(event.Nodes, event.Time),
+			"hashfull", event.Hashfull)
+	case BestMoveEvent:
+		if event.Move == (Move{}) {
+			// null move in UCI notation
+			fmt.Fprintln(listener.out, "bestmove 0000")
+			return
+		}
+		listener.printInfo(event.Score, event.Depth, event.SelDepth, event.PV, event.SearchStats)
+		if event.Ponder != (Move{}) {
+			fmt.Fprintln(listener.out, "bestmove", event.Move, "ponder", event.Ponder)
+		} else {
+			fmt.Fprintln(listener.out, "bestmove", event.Move)
+		}
+	}
+}
+
+func (listener *uciListener) maybePrintNewPvInfo(event PvEvent) {
+	if event.Time < time.Duration(200*time.Millisecond) {
 		return
 	}
-	printInfo(score, depth, bestLine, timeElapsed, debugSuffix)
+	listener.printInfo(event.Score, event.Depth, event.SelDepth, event.PV, event.SearchStats)
 }
 
-func printInfo(score, depth int, bestLine []Move, timeElapsed time.Duration, debugSuffix string) {
+func (listener *uciListener) printInfo(score, depth, selDepth int, bestLine []Move, stats SearchStats) {
 	line := Line{moves: bestLine}
-	fmt.Println("info score", formatScore(score),
+	fmt.Fprintln(listener.out, "info score", listener.formatScore(score),
 		"depth", depth,
-		"nps", nps(evaluatedNodes, timeElapsed),
-		"time", timeElapsed.Milliseconds(),
-		"nodes", evaluatedNodes,
-		"pv", line.String(),
-		debugSuffix)
+		"seldepth", selDepth,
+		"nps", nps(stats.Nodes, stats.Time),
+		"time", stats.Time.Milliseconds(),
+		"nodes", stats.Nodes,
+		"hashfull", stats.Hashfull,
+		"pv", line.String())
 }
 
-func printInfoAfterDepth(score, depth int, bestLine []Move, timeElapsed time.Duration, debugSuffix string) {
-	line := Line{moves: bestLine}
-	fmt.Println("info depth", depth,
-		"score", formatScore(score),
-		"nps", nps(evaluatedNodes, timeElapsed),
-		"time", timeElapsed.Milliseconds(),
-		"nodes", evaluatedNodes,
-		"pv", line.String(),
-		debugSuffix)
+func (listener *uciListener) printInfoAfterDepth(event IterationEvent) {
+	line := Line{moves: event.PV}
+	fmt.Fprintln(listener.out, "info depth", event.Depth,
+		"seldepth", event.SelDepth,
+		"multipv", event.MultiPv,
+		"score", listener.formatScore(event.Score),
+		"nps", nps(event.Nodes, event.Time),
+		"time", event.Time.Milliseconds(),
+		"nodes", event.Nodes,
+		"hashfull", event.Hashfull,
+		"pv", line.String())
+}
+
+// Prints result of the iteration that fell outside of the aspiration window - see aspirationSearch()
+func (listener *uciListener) printBoundInfo(event PvEvent) {
+	boundStr := "lowerbound"
+	if event.Bound == UpperBound {
+		boundStr = "upperbound"
+	}
+	line := Line{moves: event.PV}
+	fmt.Fprintln(listener.out, "info depth", event.Depth,
+		"seldepth", event.SelDepth,
+		"score", formatScore(event.Score), boundStr,
+		"nps", nps(event.Nodes, event.Time),
+		"time", event.Time.Milliseconds(),
+		"nodes", event.Nodes,
+		"hashfull", event.Hashfull,
+		"pv", line.String())
+}
+
+// Returns score as given after 'score' in info - with win/draw/loss chances if UCI_ShowWDL is on.
+func (listener *uciListener) formatScore(score int) string {
+	if !listener.showWDL {
+		return formatScore(score)
+	}
+	win, draw, loss := winDrawLoss(score)
+	return fmt.Sprintf("%v wdl %v %v %v", formatScore(score), win, draw, loss)
 }
 
 func nps(evaluatedNodes int64, timeElapsed time.Duration) int {
@@ -365,15 +608,16 @@ func parseMoveString(moveStr string) (Move, error) {
 	return NewMove(from, to), nil
 }
 
-func parsePosition(positionWithoutMoves string) {
+func (engine *Engine) parsePosition(positionWithoutMoves string) {
 	if strings.HasPrefix(positionWithoutMoves, uStartpos) {
-		posGen = NewGenerator()
+		engine.posGen = NewGenerator()
 	} else {
-		newPosGen, err := NewGeneratorFromFen(positionWithoutMoves)
+		fen := strings.TrimSpace(strings.TrimPrefix(positionWithoutMoves, uFen))
+		newPosGen, err := NewGeneratorFromFen(fen)
 		if err != nil {
-			fmt.Println("invalid FEN:", err)
+			fmt.Fprintln(engine.out, "invalid FEN:", err)
 		} else {
-			posGen = newPosGen
+			engine.posGen = newPosGen
 		}
 	}
 }
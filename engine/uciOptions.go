@@ -1,13 +1,194 @@
 package engine
 
-// TODO this will be a nice place to use go interfaces - once there are more options, of different types
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// size of transposition table in megabytes
+const (
+	hashSizeKey     string = "Hash"
+	hashSizeDefault int    = 16
+	hashSizeMin     int    = 1
+	hashSizeMax     int    = 1024
+)
+
+// button that empties transposition table
+const clearHashKey string = "Clear Hash"
+
+// number of searches running in parallel (Lazy SMP)
+const (
+	threadsKey     string = "Threads"
+	threadsDefault int    = 1
+	threadsMin     int    = 1
+	threadsMax     int    = 64
+)
+
+// number of best lines reported after each iteration
+const (
+	multiPVKey     string = "MultiPV"
+	multiPVDefault int    = 1
+	multiPVMin     int    = 1
+	multiPVMax     int    = 64
+)
+
+// tells that GUI may send 'go ponder'. When set bestmove comes with the move expected in reply
+const (
+	ponderKey     string = "Ponder"
+	ponderDefault bool   = false
+)
+
+// turns null move pruning on/off - for testing its impact on the strength
+const (
+	nullMovePruningKey     string = "NullMovePruning"
+	nullMovePruningDefault bool   = true
+)
+
+// anti 'loose on time' duration in case of delays (printing on console, GC kicking in, system clock granularity)
+const (
+	moveOverheadKey     string = "Move Overhead"
+	moveOverheadDefault int    = 50
+	moveOverheadMin     int    = 0
+	moveOverheadMax     int    = 5000
+)
 
-// log 'info currmove' everytime we run evaluation on this number of nodes(positions)
+// tells GUI that info comes with win/draw/loss chances (per mille) besides the score
 const (
-	currmoveLogIntervalKey     string = "currmoveLogInterval"
-	currmoveLogIntervalDefault int    = 1000_000
-	currmoveLogIntervalMin     int    = 10
-	currmoveLogIntervalMax     int    = 10_000_000
+	showWDLKey     string = "UCI_ShowWDL"
+	showWDLDefault bool   = false
 )
-var currmoveLogInterval int = currmoveLogIntervalDefault
 
+// values of the options set for Engine
+type options struct {
+	threadsCount    int
+	multiPV         int
+	ponderEnabled   bool
+	nullMovePruning bool
+	moveOverhead    int
+}
+
+func defaultOptions() options {
+	return options{
+		threadsCount:    threadsDefault,
+		multiPV:         multiPVDefault,
+		ponderEnabled:   ponderDefault,
+		nullMovePruning: nullMovePruningDefault,
+		moveOverhead:    moveOverheadDefault,
+	}
+}
+
+// All options of the engine in the order they are printed in response to 'uci'
+var uciOptions = []uciOption{
+	spinOption{hashSizeKey, hashSizeDefault, hashSizeMin, hashSizeMax,
+		func(engine *Engine, value int) { engine.transpositionTable.resize(value) }},
+	buttonOption{clearHashKey, func(engine *Engine) { engine.transpositionTable.Clear() }},
+	spinOption{threadsKey, threadsDefault, threadsMin, threadsMax,
+		func(engine *Engine, value int) {
+			engine.options.threadsCount = value
+			engine.searchThreads.setThreadsCount(value)
+		}},
+	checkOption{ponderKey, ponderDefault, func(engine *Engine, value bool) { engine.options.ponderEnabled = value }},
+	spinOption{multiPVKey, multiPVDefault, multiPVMin, multiPVMax,
+		func(engine *Engine, value int) { engine.options.multiPV = value }},
+	checkOption{nullMovePruningKey, nullMovePruningDefault,
+		func(engine *Engine, value bool) { engine.options.nullMovePruning = value }},
+	spinOption{moveOverheadKey, moveOverheadDefault, moveOverheadMin, moveOverheadMax,
+		func(engine *Engine, value int) { engine.options.moveOverhead = value }},
+	checkOption{showWDLKey, showWDLDefault, func(engine *Engine, value bool) { engine.uci.showWDL = value }},
+}
+
+// Option that GUI sets with 'setoption' -> https://backscattering.de/chess/uci/#engine-option
+type uciOption interface {
+	// id of the option. GUI may send it in any case
+	name() string
+	// part of the 'option' line that follows the name - type, default and allowed values
+	declaration() string
+	// Parses value and applies it to engine. Returns error if value is invalid (and ignored) or had to be
+	// clamped to the allowed range.
+	set(engine *Engine, value string) error
+}
+
+// Returns option with the name from options. Nil if there's no such option.
+func findOption(options []uciOption, name string) uciOption {
+	for _, option := range options {
+		if strings.EqualFold(option.name(), name) {
+			return option
+		}
+	}
+	return nil
+}
+
+// integer in range [min, max]
+type spinOption struct {
+	key           string
+	def, min, max int
+	apply         func(engine *Engine, value int)
+}
+
+func (option spinOption) name() string {
+	return option.key
+}
+
+func (option spinOption) declaration() string {
+	return fmt.Sprintf("type spin default %v min %v max %v", option.def, option.min, option.max)
+}
+
+func (option spinOption) set(engine *Engine, value string) error {
+	val, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", value)
+	}
+	clamped := min(max(val, option.min), option.max)
+	option.apply(engine, clamped)
+	if clamped != val {
+		return fmt.Errorf("%v is out of range %v..%v, set to %v", val, option.min, option.max, clamped)
+	}
+	return nil
+}
+
+// true or false
+type checkOption struct {
+	key   string
+	def   bool
+	apply func(engine *Engine, value bool)
+}
+
+func (option checkOption) name() string {
+	return option.key
+}
+
+func (option checkOption) declaration() string {
+	return fmt.Sprintf("type check default %v", option.def)
+}
+
+func (option checkOption) set(engine *Engine, value string) error {
+	switch strings.ToLower(value) {
+	case "true":
+		option.apply(engine, true)
+	case "false":
+		option.apply(engine, false)
+	default:
+		return fmt.Errorf("%q is neither true nor false", value)
+	}
+	return nil
+}
+
+// action without value
+type buttonOption struct {
+	key   string
+	apply func(engine *Engine)
+}
+
+func (option buttonOption) name() string {
+	return option.key
+}
+
+func (option buttonOption) declaration() string {
+	return "type button"
+}
+
+func (option buttonOption) set(engine *Engine, value string) error {
+	option.apply(engine)
+	return nil
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"bytes"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetOption(t *testing.T) {
+	var tests = []struct {
+		command string
+		// expected 'info string' error. Empty if none
+		errorText string
+		// returns true if the option has the expected value
+		check func(engine *Engine) bool
+	}{
+		{"setoption name MultiPV value 3", "", func(e *Engine) bool { return e.options.multiPV == 3 }},
+		{"setoption name multipv value 2", "", func(e *Engine) bool { return e.options.multiPV == 2 }},
+		{"setoption name MultiPV value 1000", "out of range", func(e *Engine) bool { return e.options.multiPV == multiPVMax }},
+		{"setoption name MultiPV value many", "not an integer", func(e *Engine) bool { return e.options.multiPV == multiPVDefault }},
+		{"setoption name Move Overhead value 200", "", func(e *Engine) bool { return e.options.moveOverhead == 200 }},
+		{"setoption name Ponder value true", "", func(e *Engine) bool { return e.options.ponderEnabled }},
+		{"setoption name Ponder value yes", "neither true nor false", func(e *Engine) bool { return !e.options.ponderEnabled }},
+		{"setoption name Threads value 0", "out of range", func(e *Engine) bool { return len(e.searchThreads.searches) == threadsMin }},
+		{"setoption name Hash value 2", "", func(e *Engine) bool {
+			return len(e.transpositionTable.slots) < len(NewTranspositionTable(hashSizeDefault).slots)
+		}},
+		{"setoption name Clear Hash", "", func(e *Engine) bool { return true }},
+		{"setoption name Contempt value 10", "unknown option", func(e *Engine) bool { return true }},
+		{"setoption MultiPV 3", "invalid setoption command", func(e *Engine) bool { return e.options.multiPV == multiPVDefault }},
+	}
+	for _, test := range tests {
+		t.Run(test.command, func(t *testing.T) {
+			var out bytes.Buffer
+			engine := NewEngine(&out)
+			engine.ParseInputLine(test.command)
+			if test.errorText == "" && out.Len() > 0 {
+				t.Errorf("expected no output but was %q", out.String())
+			}
+			if test.errorText != "" &&
+				(!strings.HasPrefix(out.String(), "info string") || !strings.Contains(out.String(), test.errorText)) {
+				t.Errorf("expected info string with %q but was %q", test.errorText, out.String())
+			}
+			if !test.check(engine) {
+				t.Errorf("option has unexpected value after %q", test.command)
+			}
+		})
+	}
+}
+
+// Hash and Threads resize what's there - clock and what the searches learned in the game are kept
+func TestResizeKeepsSearchState(t *testing.T) {
+	engine := NewEngine(io.Discard)
+	threads := engine.searchThreads
+	clock := &nodeClock{threads: threads, start: time.Unix(0, 0), nodeTime: time.Microsecond}
+	threads.SetClock(clock)
+	engine.ParseInputLine("position startpos")
+	engine.ParseInputLine("go depth 5")
+	engine.Wait()
+	mainSearch := threads.searches[0]
+	history := mainSearch.history
+	killers := slices.Clone(mainSearch.killerMoves)
+	tt := engine.transpositionTable
+
+	engine.ParseInputLine("setoption name Hash value 2")
+	engine.ParseInputLine("setoption name Threads value 3")
+	if engine.searchThreads != threads || threads.clock != Clock(clock) || engine.transpositionTable != tt ||
+		threads.transpositionTable != tt {
+		t.Fatalf("expected the same search threads, clock and transposition table")
+	}
+	if len(threads.searches) != 3 || threads.searches[0] != mainSearch || threads.searches[2].threads != threads {
+		t.Fatalf("expected main search kept and helpers added but was %v searches", len(threads.searches))
+	}
+	if mainSearch.history != history || !slices.Equal(mainSearch.killerMoves, killers) {
+		t.Errorf("expected history and killer moves of the main search kept")
+	}
+	if len(tt.slots) >= len(NewTranspositionTable(hashSizeDefault).slots) {
+		t.Errorf("expected smaller transposition table but has %v slots", len(tt.slots))
+	}
+	engine.ParseInputLine("setoption name Threads value 1")
+	if len(threads.searches) != 1 || threads.searches[0] != mainSearch {
+		t.Errorf("expected only the main search left but was %v searches", len(threads.searches))
+	}
+}
+
+func TestUciPrintsAllOptions(t *testing.T) {
+	var out bytes.Buffer
+	NewEngine(&out).ParseInputLine("uci")
+	for _, expected := range []string{
+		"option name Hash type spin default 16 min 1 max 1024\n",
+		"option name Clear Hash type button\n",
+		"option name Ponder type check default false\n",
+		"option name Move Overhead type spin default 50 min 0 max 5000\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%v", expected, out.String())
+		}
+	}
+	if strings.Count(out.String(), "option name") != len(uciOptions) || !strings.HasSuffix(out.String(), "uciok\n") {
+		t.Errorf("unexpected output:\n%v", out.String())
+	}
+}
@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Search after ucinewgame must not depend on anything searched before it
+func TestUciNewGame(t *testing.T) {
+	limits := SearchLimits{Nodes: 30_000}
+	game, _ := NewGameFromFen("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	expected, _ := NewEngine(io.Discard).Search(context.Background(), game, limits)
+
+	engine := NewEngine(io.Discard)
+	engine.ParseInputLine("position startpos moves d2d4 d7d5 c2c4")
+	engine.ParseInputLine("go depth 6")
+	engine.Wait()
+	engine.ParseInputLine("ucinewgame")
+	if engine.posGen != nil {
+		t.Errorf("position of the previous game was kept")
+	}
+	actual, _ := engine.Search(context.Background(), game, limits)
+	if actual.Nodes != expected.Nodes || actual.Depth != expected.Depth || !slices.Equal(actual.PV, expected.PV) {
+		t.Errorf("search after ucinewgame %+v differs from the search of a new engine %+v", actual, expected)
+	}
+}
+
+func TestDebug(t *testing.T) {
+	var out bytes.Buffer
+	engine := NewEngine(&out)
+	engine.ParseInputLine("position startpos")
+	engine.ParseInputLine("go depth 1")
+	engine.Wait()
+	engine.ParseInputLine("register later")
+	if strings.Contains(out.String(), "info string") {
+		t.Errorf("expected no diagnostics before 'debug on' but was:\n%v", out.String())
+	}
+
+	out.Reset()
+	engine.ParseInputLine("debug on")
+	engine.ParseInputLine("go wtime 60000 btime 60000")
+	engine.ParseInputLine("stop")
+	engine.Wait()
+	for _, expected := range []string{"info string static eval", "info string time optimum"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in output:\n%v", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	engine.ParseInputLine("debug off")
+	engine.ParseInputLine("go depth 1")
+	engine.Wait()
+	if strings.Contains(out.String(), "info string") {
+		t.Errorf("expected no diagnostics after 'debug off' but was:\n%v", out.String())
+	}
+}
+
+func TestIsReadyWhileSearching(t *testing.T) {
+	var out bytes.Buffer
+	engine := NewEngine(&out)
+	engine.ParseInputLine("position startpos")
+	engine.ParseInputLine("go infinite")
+	threads := engine.searchThreads
+	engine.ParseInputLine("isready")
+	engine.ParseInputLine("stop")
+	engine.Wait()
+	if engine.searchThreads != threads {
+		t.Errorf("isready replaced the searches")
+	}
+	readyIdx, bestMoveIdx := strings.Index(out.String(), "readyok"), strings.Index(out.String(), "bestmove")
+	if readyIdx < 0 || bestMoveIdx < 0 || readyIdx > bestMoveIdx {
+		t.Errorf("expected readyok while searching and bestmove after stop but was:\n%v", out.String())
+	}
+}
+
+// Stop is checked at every node so that searches end right after it
+func TestStopLatency(t *testing.T) {
+	const threadsCount = 4
+	const maxNodesAfterStop = 200 * threadsCount
+	engine := NewEngine(io.Discard)
+	engine.ParseInputLine("setoption name Threads value 4")
+	engine.ParseInputLine("position startpos moves e2e4 e7e5 g1f3 b8c6 f1b5")
+	engine.ParseInputLine("go infinite")
+	threads := engine.searchThreads
+	for threads.evaluatedNodes() < 100_000 {
+		time.Sleep(time.Millisecond)
+	}
+	engine.ParseInputLine("stop")
+	nodesAtStop := threads.evaluatedNodes()
+	engine.Wait()
+	if nodes := threads.evaluatedNodes() - nodesAtStop; nodes > maxNodesAfterStop {
+		t.Errorf("%v nodes evaluated after stop - expected at most %v", nodes, maxNodesAfterStop)
+	}
+}
+
+// Commands sent while searching must not race with the search. Run with -race
+func TestCommandLoopStress(t *testing.T) {
+	var out bytes.Buffer
+	engine := NewEngine(&out)
+	script := []string{
+		"setoption name Threads value 3",
+		"position startpos moves e2e4",
+		"go depth 4",
+		"position startpos moves d2d4 d7d5",
+		"setoption name MultiPV value 2",
+		"isready",
+		"go nodes 5000",
+		"debug on",
+		"stop",
+		"ucinewgame",
+		"setoption name Hash value 2",
+		"position fen 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1",
+		"go ponder movetime 20",
+		"ponderhit",
+		"setoption name Clear Hash",
+		"eval",
+		"go movetime 10",
+		"isready",
+		"stop",
+		"debug off",
+	}
+	goCommands := 0
+	for i := 0; i < 10; i++ {
+		for _, command := range script {
+			engine.ParseInputLine(command)
+			if strings.HasPrefix(command, "go") {
+				goCommands++
+			}
+		}
+	}
+	engine.Wait()
+	if bestMoves := strings.Count(out.String(), "bestmove"); bestMoves != goCommands {
+		t.Fatalf("expected %v bestmoves for every go but was %v", goCommands, bestMoves)
+	}
+
+	engine.ParseInputLine("position startpos")
+	engine.ParseInputLine("go infinite")
+	engine.ParseInputLine("go depth 3")
+	engine.ParseInputLine("quit")
+	if !engine.HasQuit() {
+		t.Fatalf("engine did not quit")
+	}
+	// queued search is dropped on quit
+	if bestMoves := strings.Count(out.String(), "bestmove"); bestMoves != goCommands+1 {
+		t.Fatalf("expected only the infinite search to print bestmove after quit but was %v of them", bestMoves-goCommands)
+	}
+}
+
+func TestInfoOutput(t *testing.T) {
+	var out bytes.Buffer
+	engine := NewEngine(&out)
+	engine.ParseInputLine("setoption name UCI_ShowWDL value true")
+	engine.ParseInputLine("position startpos moves e2e4 e7e5 g1f3 b8c6 f1b5 a7a6 b5a4 g8f6")
+	// simulated clock passes currMoveInfoMinTime in the middle of the search
+	threads := engine.searchThreads
+	threads.SetClock(&nodeClock{threads: threads, start: time.Unix(0, 0), nodeTime: 200 * time.Microsecond})
+	engine.ParseInputLine("go depth 7")
+	engine.Wait()
+
+	var iterations, currMoves int
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		fields := strings.Fields(line)
+		if strings.Contains(line, "multipv") {
+			iterations++
+			var depth, selDepth int
+			fmt.Sscanf(line, "info depth %d seldepth %d", &depth, &selDepth)
+			if selDepth < depth {
+				t.Errorf("seldepth below depth in %q", line)
+			}
+			if !strings.Contains(line, " wdl ") || !slices.Contains(fields, "hashfull") {
+				t.Errorf("expected wdl and hashfull in %q", line)
+			}
+		}
+		if slices.Contains(fields, "currmove") {
+			currMoves++
+			if !slices.Contains(fields, "currmovenumber") {
+				t.Errorf("expected currmovenumber in %q", line)
+			}
+		}
+	}
+	if iterations == 0 || currMoves == 0 {
+		t.Errorf("expected info after iterations and currmove but was:\n%v", out.String())
+	}
+}
+
+// Lines cut off by transposition table, repetitions or mates end before the horizon - seldepth is still
+// never reported below depth
+func TestSelDepthNotBelowDepth(t *testing.T) {
+	var tests = []string{
+		"8/k7/3p4/p2P1p2/P2P1P2/8/8/K7 w - - 0 1",
+		"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1",
+		"r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1",
+	}
+	for _, fen := range tests {
+		var out bytes.Buffer
+		engine := NewEngine(&out)
+		engine.ParseInputLine("position fen " + fen)
+		engine.ParseInputLine("go nodes 100000")
+		engine.Wait()
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			fields := strings.Fields(line)
+			depthIdx, selDepthIdx := slices.Index(fields, "depth"), slices.Index(fields, "seldepth")
+			if depthIdx == -1 || selDepthIdx == -1 {
+				continue
+			}
+			depth, _ := strconv.Atoi(fields[depthIdx+1])
+			selDepth, _ := strconv.Atoi(fields[selDepthIdx+1])
+			if selDepth < depth {
+				t.Errorf("%v: seldepth below depth in %q", fen, line)
+			}
+		}
+	}
+}
+
+// infinite search prints bestmove only after 'stop' - also when there is just one move to search
+func TestGoInfinite(t *testing.T) {
+	for _, goCommand := range []string{"go infinite searchmoves e2e4", "go infinite depth 2"} {
+		var out bytes.Buffer
+		engine := NewEngine(&out)
+		engine.ParseInputLine("position startpos")
+		engine.ParseInputLine(goCommand)
+		time.Sleep(300 * time.Millisecond)
+		engine.commandMutex.Lock()
+		searchRunning := engine.searchRunning
+		engine.commandMutex.Unlock()
+		if !searchRunning {
+			t.Errorf("%v: search ended before 'stop'", goCommand)
+		}
+		engine.ParseInputLine("stop")
+		engine.Wait()
+		if !strings.Contains(out.String(), "bestmove") {
+			t.Errorf("%v: expected bestmove after stop but was:\n%v", goCommand, out.String())
+		}
+	}
+}
+
+func TestGoMissingValue(t *testing.T) {
+	for _, goCommand := range []string{"go nodes", "go mate", "go depth", "go movetime", "go wtime 1000 btime",
+		"go movestogo", "go depth x"} {
+		var out bytes.Buffer
+		engine := NewEngine(&out)
+		engine.ParseInputLine("position startpos")
+		engine.ParseInputLine(goCommand)
+		engine.Wait()
+		if !strings.Contains(out.String(), "info string invalid go command") || strings.Contains(out.String(), "bestmove") {
+			t.Errorf("%v: expected the command rejected but was:\n%v", goCommand, out.String())
+		}
+	}
+}
+
+// time kept for the overhead never leaves the search with a deadline in the past
+func TestShortMoveTime(t *testing.T) {
+	var tests = []struct {
+		moveTimeMillis int
+		expectedMillis int
+	}{
+		{0, 1},
+		{10, 1},
+		{moveOverheadDefault, 1},
+		{moveOverheadDefault + 1, 1},
+		{moveOverheadDefault + 20, 20},
+		{1000, 1000 - moveOverheadDefault},
+	}
+	engine := NewEngine(io.Discard)
+	engine.ParseInputLine("position startpos")
+	startTime := time.Unix(0, 0)
+	for _, test := range tests {
+		limits, ok := engine.parseGoLimits(fmt.Sprintf("movetime %v", test.moveTimeMillis), startTime)
+		expected := time.Duration(test.expectedMillis) * time.Millisecond
+		if !ok || limits.timeManager.maximum != expected || !limits.endTime.Equal(startTime.Add(expected)) {
+			t.Errorf("movetime %v: expected %v to search but was %v (end time %v)", test.moveTimeMillis, expected,
+				limits.timeManager.maximum, limits.endTime.Sub(startTime))
+		}
+	}
+}
+
+func TestGoInvalidSearchMoves(t *testing.T) {
+	var tests = []struct {
+		goCommand        string
+		expectedInfo     string
+		expectedBestMove string
+	}{
+		{"go searchmoves e2e5 d2d4 depth 2", "info string illegal searchmoves move: e2e5", "bestmove d2d4"},
+		{"go searchmoves d2d4 xyz depth 2", "info string invalid searchmoves move: xyz", "bestmove d2d4"},
+		{"go depth 2 searchmoves e2e5", "info string illegal searchmoves move: e2e5", "bestmove "},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		engine := NewEngine(&out)
+		engine.ParseInputLine("position startpos")
+		engine.ParseInputLine(test.goCommand)
+		engine.Wait()
+		if !strings.Contains(out.String(), test.expectedInfo) || !strings.Contains(out.String(), test.expectedBestMove) {
+			t.Errorf("%v: expected %q and %q but was:\n%v", test.goCommand, test.expectedInfo, test.expectedBestMove,
+				out.String())
+		}
+	}
+}
+
+func TestGoNoLegalMoves(t *testing.T) {
+	for _, fen := range []string{"R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1", "k7/2Q5/1K6/8/8/8/8/8 b - - 0 1"} {
+		var out bytes.Buffer
+		engine := NewEngine(&out)
+		engine.ParseInputLine("position fen " + fen)
+		engine.ParseInputLine("go depth 3")
+		engine.Wait()
+		if !strings.Contains(out.String(), "bestmove 0000\n") {
+			t.Errorf("%v: expected null bestmove but was:\n%v", fen, out.String())
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package engine
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	xXboard   string = "xboard"
+	xProtover string = "protover"
+	xNew      string = "new"
+	xForce    string = "force"
+	xGo       string = "go"
+	xUserMove string = "usermove"
+	xLevel    string = "level"
+	xSt       string = "st"
+	xSd       string = "sd"
+	xTime     string = "time"
+	xOtim     string = "otim"
+	xUndo     string = "undo"
+	xRemove   string = "remove"
+	xSetBoard string = "setboard"
+	xPost     string = "post"
+	xNoPost   string = "nopost"
+	xResult   string = "result"
+	xPing     string = "ping"
+	xMoveNow  string = "?"
+	xQuit     string = "quit"
+)
+
+// time control until 'level' is given - 40 moves in 5 minutes
+const (
+	xboardMovesPerSessionDefault int = 40
+	xboardBaseMillisDefault      int = 5 * 60 * 1000
+)
+
+// Game played in XBoard protocol (CECP). Unlike in UCI the engine keeps track of the game and the clock
+// itself and decides when to think.
+type xboardState struct {
+	// prints thinking output
+	listener *xboardListener
+	// set by 'force' - moves are only played on the board then
+	forceMode   bool
+	engineWhite bool
+	// 'level' time control. 0 moves per session for the whole game in base time
+	movesPerSession int
+	baseMillis      int
+	incMillis       int
+	// ply of the position when the time control started - at 'level', 'new' or 'setboard'. Sessions are
+	// counted from it, not from the fullmove number of the position
+	sessionStartPly int16
+	// set by 'st' - fixed time per move. 0 when playing on the clock
+	moveTimeMillis int
+	// set by 'sd'. 0 for no limit
+	maxDepth int
+	// set by 'time' and 'otim'
+	engineMillisLeft   int
+	opponentMillisLeft int
+	// position set by 'new' or 'setboard' and the moves played since. Needed to undo the moves
+	startFen string
+	moves    []Move
+	// set when the running search was interrupted by a command that changes the game. Its move is not played
+	discardMove bool
+}
+
+// Switches the engine to XBoard protocol. Thinking output replaces UCI info.
+func (engine *Engine) startXboard() {
+	listener := &xboardListener{out: engine.out}
+	engine.xboard = &xboardState{
+		listener:        listener,
+		movesPerSession: xboardMovesPerSessionDefault,
+		baseMillis:      xboardBaseMillisDefault,
+	}
+	engine.listeners[0] = listener
+	engine.doXboardNew()
+}
+
+func (engine *Engine) executeXboardCommand(inputLine string) {
+	command, args, _ := strings.Cut(inputLine, " ")
+	args = strings.TrimSpace(args)
+	xboard := engine.xboard
+	switch command {
+	case xProtover:
+		fmt.Fprintf(engine.out, "feature myname=\"Magog %v\" usermove=1 setboard=1 ping=1 colors=0 sigint=0 sigterm=0 done=1\n",
+			VERSION_STRING)
+	case xNew:
+		engine.doXboardNew()
+	case xForce:
+		xboard.forceMode = true
+	case xGo:
+		xboard.forceMode = false
+		xboard.engineWhite = engine.xboardGame().WhiteToMove()
+		engine.xboardThink()
+	case xUserMove:
+		engine.doXboardUserMove(args)
+	case xLevel:
+		engine.doLevel(inputLine)
+	case xSt:
+		seconds, err := strconv.ParseFloat(args, 64)
+		if err != nil || seconds <= 0 {
+			xboardError(engine.out, "invalid time", inputLine)
+			return
+		}
+		xboard.moveTimeMillis = int(seconds * 1000)
+	case xSd:
+		depth, err := strconv.Atoi(args)
+		if err != nil || depth < 1 {
+			xboardError(engine.out, "invalid depth", inputLine)
+			return
+		}
+		xboard.maxDepth = min(depth, MaxSearchDepth)
+	case xTime, xOtim:
+		centis, err := strconv.Atoi(args)
+		if err != nil {
+			xboardError(engine.out, "invalid time", inputLine)
+			return
+		}
+		if command == xTime {
+			xboard.engineMillisLeft = centis * 10
+		} else {
+			xboard.opponentMillisLeft = centis * 10
+		}
+	case xUndo:
+		engine.undoXboardMoves(1, inputLine)
+	case xRemove:
+		engine.undoXboardMoves(2, inputLine)
+	case xSetBoard:
+		gen, err := NewGeneratorFromFen(args)
+		if err != nil {
+			fmt.Fprintln(engine.out, "tellusererror Illegal position")
+			return
+		}
+		engine.setXboardPosition(gen, args, nil)
+		xboard.sessionStartPly = gen.getTopPos().ply
+	case xPost:
+		xboard.listener.post.Store(true)
+	case xNoPost:
+		xboard.listener.post.Store(false)
+	case xResult:
+		// game is over - the search (if any) was interrupted already
+		xboard.forceMode = true
+	case xPing:
+		fmt.Fprintln(engine.out, "pong", args)
+	case xMoveNow:
+		engine.searchThreads.Stop()
+	case xQuit:
+		engine.quit = true
+		engine.searchThreads.Stop()
+	case xXboard, "accepted", "rejected", "random", "easy", "hard", "computer", "name", "rating", "ics", "draw":
+		// nothing to do - the engine neither ponders nor plays differently against anyone
+	default:
+		// moves without 'usermove' prefix are sent by GUIs that ignored the feature
+		if _, err := parseMoveString(command); err == nil && args == "" {
+			engine.doXboardUserMove(command)
+			return
+		}
+		xboardError(engine.out, "unknown command", inputLine)
+	}
+}
+
+// Returns true for commands executed at once while the engine thinks. The others wait for its move.
+func isXboardExecutableWhileSearching(inputLine string) bool {
+	command, _, _ := strings.Cut(inputLine, " ")
+	switch command {
+	case xMoveNow, xQuit, xPost, xNoPost, xTime, xOtim, "easy", "hard":
+		return true
+	}
+	return false
+}
+
+// Returns true for commands that change the game or the side the engine plays. The move of the search they
+// come during would be made in the game that is no longer there.
+func interruptsXboardSearch(inputLine string) bool {
+	command, _, _ := strings.Cut(inputLine, " ")
+	switch command {
+	case xNew, xForce, xResult, xUndo, xRemove, xSetBoard:
+		return true
+	}
+	return false
+}
+
+func xboardError(out io.Writer, errorType, command string) {
+	fmt.Fprintf(out, "Error (%v): %v\n", errorType, command)
+}
+
+// Returns the game in engine's position. Shares the generator with the engine.
+func (engine *Engine) xboardGame() *Game {
+	return &Game{gen: engine.posGen}
+}
+
+// Starts new game in the starting position. Engine plays black and forgets everything learned in the previous game.
+func (engine *Engine) doXboardNew() {
+	engine.doUciNewGame()
+	xboard := engine.xboard
+	xboard.forceMode = false
+	xboard.engineWhite = false
+	xboard.maxDepth = 0
+	xboard.engineMillisLeft = xboard.baseMillis
+	xboard.opponentMillisLeft = xboard.baseMillis
+	gen := NewGenerator()
+	engine.setXboardPosition(gen, gen.getTopPos().Fen(), nil)
+	xboard.sessionStartPly = gen.getTopPos().ply
+}
+
+func (engine *Engine) setXboardPosition(gen *Generator, startFen string, moves []Move) {
+	engine.posGen = gen
+	engine.xboard.startFen = startFen
+	engine.xboard.moves = moves
+	engine.searchThreads.clearKillerMoves()
+}
+
+// Applies 'level MPS BASE INC' where BASE is given in minutes (or minutes:seconds) and INC in seconds.
+func (engine *Engine) doLevel(levelCommand string) {
+	tokens := strings.Fields(strings.TrimPrefix(levelCommand, xLevel))
+	if len(tokens) != 3 {
+		xboardError(engine.out, "invalid level", levelCommand)
+		return
+	}
+	movesPerSession, err := strconv.Atoi(tokens[0])
+	if err != nil || movesPerSession < 0 {
+		xboardError(engine.out, "invalid level", levelCommand)
+		return
+	}
+	minutes, seconds, hasSeconds := strings.Cut(tokens[1], ":")
+	baseMinutes, err := strconv.Atoi(minutes)
+	if err != nil {
+		xboardError(engine.out, "invalid level", levelCommand)
+		return
+	}
+	baseSeconds := 0
+	if hasSeconds {
+		baseSeconds, err = strconv.Atoi(seconds)
+		if err != nil {
+			xboardError(engine.out, "invalid level", levelCommand)
+			return
+		}
+	}
+	incSeconds, err := strconv.ParseFloat(tokens[2], 64)
+	if err != nil || incSeconds < 0 {
+		xboardError(engine.out, "invalid level", levelCommand)
+		return
+	}
+	xboard := engine.xboard
+	xboard.movesPerSession = movesPerSession
+	xboard.baseMillis = (baseMinutes*60 + baseSeconds) * 1000
+	xboard.incMillis = int(incSeconds * 1000)
+	xboard.sessionStartPly = engine.posGen.getTopPos().ply
+	// playing on the clock from now on
+	xboard.moveTimeMillis = 0
+	xboard.engineMillisLeft = xboard.baseMillis
+	xboard.opponentMillisLeft = xboard.baseMillis
+}
+
+func (engine *Engine) doXboardUserMove(moveStr string) {
+	parsed, err := parseMoveString(moveStr)
+	if err != nil {
+		fmt.Fprintln(engine.out, "Illegal move:", moveStr)
+		return
+	}
+	move, ok := engine.posGen.findLegalMove(parsed)
+	if !ok {
+		fmt.Fprintln(engine.out, "Illegal move:", moveStr)
+		return
+	}
+	engine.playXboardMove(move)
+	xboard := engine.xboard
+	if !xboard.forceMode && xboard.engineWhite == engine.xboardGame().WhiteToMove() {
+		engine.xboardThink()
+	}
+}
+
+func (engine *Engine) playXboardMove(move Move) {
+	engine.posGen.ApplyUciMove(move)
+	engine.xboard.moves = append(engine.xboard.moves, move)
+}
+
+// Takes back the last count moves - by replaying the rest of them from the start position.
+func (engine *Engine) undoXboardMoves(count int, command string) {
+	xboard := engine.xboard
+	if count > len(xboard.moves) {
+		xboardError(engine.out, "no moves to undo", command)
+		return
+	}
+	gen, err := NewGeneratorFromFen(xboard.startFen)
+	if err != nil {
+		panic(fmt.Sprintf("start position %v was valid when set: %v", xboard.startFen, err))
+	}
+	moves := xboard.moves[:len(xboard.moves)-count]
+	for _, move := range moves {
+		gen.ApplyUciMove(move)
+	}
+	engine.setXboardPosition(gen, xboard.startFen, moves)
+}
+
+// Starts thinking on the engine's move unless the game is over.
+func (engine *Engine) xboardThink() {
+	if engine.claimXboardResult() {
+		return
+	}
+	startTime := engine.searchThreads.clock.Now()
+	engine.startSearch(engine.xboardLimits(startTime), engine.makeXboardMove)
+}
+
+func (engine *Engine) makeXboardMove(best *Search) {
+	xboard := engine.xboard
+	if xboard.discardMove || engine.quit {
+		xboard.discardMove = false
+		return
+	}
+	if len(best.bestLine.moves) == 0 {
+		// game already over - no move to make
+		engine.claimXboardResult()
+		return
+	}
+	move := best.bestLine.moves[0]
+	engine.playXboardMove(move)
+	fmt.Fprintln(engine.out, "move", move)
+	engine.claimXboardResult()
+}
+
+// Prints the result when the game has ended in the engine's position. Returns true then.
+func (engine *Engine) claimXboardResult() bool {
+	game := engine.xboardGame()
+	switch game.Status() {
+	case Checkmate:
+		if game.WhiteToMove() {
+			fmt.Fprintln(engine.out, "0-1 {Black mates}")
+		} else {
+			fmt.Fprintln(engine.out, "1-0 {White mates}")
+		}
+	case Stalemate:
+		fmt.Fprintln(engine.out, "1/2-1/2 {Stalemate}")
+	case DrawByFiftyMoveRule:
+		fmt.Fprintln(engine.out, "1/2-1/2 {Draw by fifty move rule}")
+	case DrawByThreefoldRepetition:
+		fmt.Fprintln(engine.out, "1/2-1/2 {Draw by repetition}")
+	default:
+		return false
+	}
+	return true
+}
+
+// Returns limits of the search for the engine's move: fixed time set by 'st' or time planned for the move
+// from the clock and 'level' time control. Depth is limited by 'sd'.
+func (engine *Engine) xboardLimits(startTime time.Time) searchLimits {
+	xboard := engine.xboard
+	limits := newSearchLimits(time.Time{})
+	if xboard.maxDepth > 0 {
+		limits.maxDepth = xboard.maxDepth
+	}
+	if xboard.moveTimeMillis > 0 {
+		moveTimeMillis := max(xboard.moveTimeMillis-engine.options.moveOverhead, 1)
+		limits.timeManager = newFixedTimeManager(time.Duration(moveTimeMillis) * time.Millisecond)
+	} else {
+		fullMovesToGo := ExpectedFullMovesToBePlayed
+		if xboard.movesPerSession > 0 {
+			// moves taken back before the start of the session count as not played
+			fullMovesPlayed := max(int(engine.posGen.getTopPos().ply-xboard.sessionStartPly), 0) / 2
+			fullMovesToGo = xboard.movesPerSession - fullMovesPlayed%xboard.movesPerSession
+		}
+		limits.timeManager = newTimeManager(xboard.engineMillisLeft, xboard.incMillis, fullMovesToGo,
+			engine.options.moveOverhead)
+	}
+	limits.startTime = startTime
+	limits.endTime = startTime.Add(limits.timeManager.maximum)
+	return limits
+}
+
+// Front-end of the search that prints thinking output of XBoard protocol: depth, score, time in centiseconds,
+// nodes and principal variation. Best move is made by the engine itself - see makeXboardMove().
+type xboardListener struct {
+	out io.Writer
+	// set by 'post', cleared by 'nopost'
+	post atomic.Bool
+}
+
+func (listener *xboardListener) OnSearchEvent(event SearchEvent) {
+	if !listener.post.Load() {
+		return
+	}
+	switch event := event.(type) {
+	case IterationEvent:
+		if event.MultiPv == 1 {
+			listener.printThinking(event.Depth, event.Score, event.PV, event.SearchStats)
+		}
+	case PvEvent:
+		if event.Bound == ExactScore && event.Time >= 200*time.Millisecond {
+			listener.printThinking(event.Depth, event.Score, event.PV, event.SearchStats)
+		}
+	}
+}
+
+func (listener *xboardListener) printThinking(depth, score int, pv []Move, stats SearchStats) {
+	line := Line{moves: pv}
+	fmt.Fprintln(listener.out, depth, xboardScore(score), stats.Time.Milliseconds()/10, stats.Nodes, line.String())
+}
+
+// Returns score in centipawns. Mate in N moves is 100000+N (or -100000-N when getting mated).
+func xboardScore(score int) int {
+	if !closeToMate(score) {
+		return score
+	}
+	movesToMate := fullMovesToMate(score)
+	if movesToMate > 0 {
+		return 100000 + movesToMate
+	}
+	return -100000 + movesToMate
+}
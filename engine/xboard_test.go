@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newXboardEngine(out *bytes.Buffer) *Engine {
+	engine := NewEngine(out)
+	engine.ParseInputLine("xboard")
+	engine.ParseInputLine("protover 2")
+	engine.ParseInputLine("new")
+	return engine
+}
+
+func TestXboardGame(t *testing.T) {
+	var out bytes.Buffer
+	engine := newXboardEngine(&out)
+	if !strings.Contains(out.String(), "usermove=1") || !strings.Contains(out.String(), "done=1") {
+		t.Fatalf("expected features after protover but was:\n%v", out.String())
+	}
+	engine.ParseInputLine("sd 4")
+	engine.ParseInputLine("usermove e2e4")
+	engine.Wait()
+	if !regexp.MustCompile(`(?m)^move [a-h][1-8][a-h][1-8]$`).MatchString(out.String()) {
+		t.Fatalf("expected engine's reply to e2e4 but was:\n%v", out.String())
+	}
+	if len(engine.xboard.moves) != 2 || engine.xboardGame().WhiteToMove() != true {
+		t.Errorf("expected both moves played but was %v", engine.xboard.moves)
+	}
+	if strings.Contains(out.String(), "bestmove") || strings.Contains(out.String(), "info") {
+		t.Errorf("expected no UCI output but was:\n%v", out.String())
+	}
+}
+
+func TestXboardCommands(t *testing.T) {
+	var tests = []struct {
+		commands    []string
+		expectedFen string
+		expectedOut string
+	}{
+		{[]string{"force", "usermove e2e4", "usermove e7e5"},
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2", ""},
+		{[]string{"force", "e2e4"},
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", ""},
+		{[]string{"force", "usermove e2e5"},
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Illegal move: e2e5"},
+		{[]string{"force", "usermove e2e4", "usermove e7e5", "undo"},
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", ""},
+		{[]string{"force", "usermove e2e4", "usermove e7e5", "remove"},
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", ""},
+		{[]string{"force", "usermove e2e4", "remove"},
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "Error (no moves to undo): remove"},
+		{[]string{"force", "setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "usermove a1a2", "undo"},
+			"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", ""},
+		{[]string{"setboard 6k1/5ppp/8/8 w - - 0 1"},
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "tellusererror Illegal position"},
+		{[]string{"ping 7"},
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "pong 7"},
+		{[]string{"level 40 5:x 0"},
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Error (invalid level): level 40 5:x 0"},
+		{[]string{"foo"},
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Error (unknown command): foo"},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		engine := newXboardEngine(&out)
+		out.Reset()
+		for _, command := range test.commands {
+			engine.ParseInputLine(command)
+		}
+		engine.Wait()
+		if fen := engine.posGen.getTopPos().Fen(); fen != test.expectedFen {
+			t.Errorf("%v: expected position %v but was %v", test.commands, test.expectedFen, fen)
+		}
+		if !strings.Contains(out.String(), test.expectedOut) {
+			t.Errorf("%v: expected %q in output:\n%v", test.commands, test.expectedOut, out.String())
+		}
+		if strings.Contains(out.String(), "move ") {
+			t.Errorf("%v: expected no engine move but was:\n%v", test.commands, out.String())
+		}
+	}
+}
+
+func TestXboardMateAndResult(t *testing.T) {
+	var out bytes.Buffer
+	engine := newXboardEngine(&out)
+	engine.ParseInputLine("force")
+	engine.ParseInputLine("setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	engine.ParseInputLine("post")
+	engine.ParseInputLine("sd 4")
+	engine.ParseInputLine("go")
+	engine.Wait()
+	if !strings.Contains(out.String(), "move a1a8\n1-0 {White mates}\n") {
+		t.Fatalf("expected mate with result claimed but was:\n%v", out.String())
+	}
+	// ply score time nodes pv - mate in 1 move for the engine
+	if !regexp.MustCompile(`(?m)^\d+ 100001 \d+ \d+ a1a8$`).MatchString(out.String()) {
+		t.Errorf("expected thinking output with mate score but was:\n%v", out.String())
+	}
+
+	out.Reset()
+	engine.ParseInputLine("result 1-0 {White mates}")
+	engine.ParseInputLine("setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	engine.ParseInputLine("nopost")
+	engine.ParseInputLine("go")
+	engine.Wait()
+	if strings.Contains(out.String(), "100001") {
+		t.Errorf("expected no thinking output after nopost but was:\n%v", out.String())
+	}
+}
+
+func TestXboardNoLegalMoves(t *testing.T) {
+	var out bytes.Buffer
+	engine := newXboardEngine(&out)
+	engine.ParseInputLine("force")
+	engine.ParseInputLine("setboard R5k1/5ppp/8/8/8/8/5PPP/6K1 b - - 0 1")
+	out.Reset()
+	engine.ParseInputLine("go")
+	engine.Wait()
+	if out.String() != "1-0 {White mates}\n" {
+		t.Errorf("expected only the result claimed but was:\n%v", out.String())
+	}
+	// search that found no move ends with the result too
+	out.Reset()
+	engine.makeXboardMove(NewSearch(engine.searchThreads, 0))
+	if out.String() != "1-0 {White mates}\n" {
+		t.Errorf("expected result claimed instead of a move but was:\n%v", out.String())
+	}
+}
+
+func TestXboardInterrupt(t *testing.T) {
+	var out bytes.Buffer
+	engine := newXboardEngine(&out)
+	engine.ParseInputLine("level 0 60 0")
+	engine.ParseInputLine("go")
+	engine.ParseInputLine("force")
+	engine.Wait()
+	if strings.Contains(out.String(), "move ") || len(engine.xboard.moves) != 0 {
+		t.Errorf("expected move of the interrupted search discarded but was:\n%v", out.String())
+	}
+
+	engine.ParseInputLine("go")
+	engine.ParseInputLine("?")
+	engine.ParseInputLine("ping 3")
+	engine.Wait()
+	if !regexp.MustCompile(`(?m)^move [a-h][1-8][a-h][1-8]\npong 3$`).MatchString(out.String()) {
+		t.Errorf("expected move made at once and then pong but was:\n%v", out.String())
+	}
+}
+
+func TestXboardLimits(t *testing.T) {
+	var tests = []struct {
+		commands            []string
+		expectedTimeManager timeManager
+		expectedMaxDepth    int
+	}{
+		{[]string{}, newTimeManager(5*60*1000, 0, 40, moveOverheadDefault), MaxSearchDepth},
+		{[]string{"level 40 5 0", "force", "e2e4", "e7e5", "time 6000", "sd 7"},
+			newTimeManager(60_000, 0, 39, moveOverheadDefault), 7},
+		// session starts at the position set, not at its fullmove number
+		{[]string{"level 40 5 0", "setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 30", "time 6000"},
+			newTimeManager(60_000, 0, 40, moveOverheadDefault), MaxSearchDepth},
+		{[]string{"force", "setboard 6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 30", "level 40 5 0", "a1a2", "g8h8", "time 6000"},
+			newTimeManager(60_000, 0, 39, moveOverheadDefault), MaxSearchDepth},
+		{[]string{"level 0 2:30 1.5", "time 9000"},
+			newTimeManager(90_000, 1500, ExpectedFullMovesToBePlayed, moveOverheadDefault), MaxSearchDepth},
+		{[]string{"level 0 2:30 1.5", "st 2"},
+			newFixedTimeManager(time.Duration(2000-moveOverheadDefault) * time.Millisecond), MaxSearchDepth},
+		{[]string{"st 2", "level 0 2 0"},
+			newTimeManager(2*60*1000, 0, ExpectedFullMovesToBePlayed, moveOverheadDefault), MaxSearchDepth},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		engine := newXboardEngine(&out)
+		for _, command := range test.commands {
+			engine.ParseInputLine(command)
+		}
+		startTime := time.Unix(0, 0)
+		limits := engine.xboardLimits(startTime)
+		if limits.timeManager != test.expectedTimeManager {
+			t.Errorf("%v: expected time manager %+v but was %+v", test.commands, test.expectedTimeManager, limits.timeManager)
+		}
+		if limits.maxDepth != test.expectedMaxDepth {
+			t.Errorf("%v: expected max depth %v but was %v", test.commands, test.expectedMaxDepth, limits.maxDepth)
+		}
+		if !limits.endTime.Equal(startTime.Add(limits.timeManager.maximum)) {
+			t.Errorf("%v: expected end time at the maximum time of the move", test.commands)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Zobrist keys -> https://www.chessprogramming.org/Zobrist_Hashing
+// Position.hash is a xor of keys below. MakeMove() keeps it up to date by xoring in/out only what has changed.
+var (
+	// indexed [zobristPieceIdx(piece)][square]. 0x88 squares so half of the entries are never used
+	zobristPieces [12][128]uint64
+	// indexed by castling bits of Position.flags shifted to 0-15 range
+	zobristCastling [16]uint64
+	// indexed by file of the en passant square
+	zobristEnPassant [8]uint64
+	zobristBlackTurn uint64
+)
+
+// fixed seed so that hashes are the same between runs (makes debugging and book/table files possible)
+const zobristSeed uint64 = 0x6D61676F67_2024
+
+func init() {
+	initZobristKeys()
+}
+
+func initZobristKeys() {
+	rng := xorshift64{state: zobristSeed}
+	for p := range zobristPieces {
+		for sq := range zobristPieces[p] {
+			zobristPieces[p][sq] = rng.next()
+		}
+	}
+	for i := range zobristCastling {
+		zobristCastling[i] = rng.next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = rng.next()
+	}
+	zobristBlackTurn = rng.next()
+}
+
+// https://www.chessprogramming.org/Pseudo_Random_Number_Generator - good enough for zobrist keys
+type xorshift64 struct {
+	state uint64
+}
+
+func (rng *xorshift64) next() uint64 {
+	rng.state ^= rng.state >> 12
+	rng.state ^= rng.state << 25
+	rng.state ^= rng.state >> 27
+	return rng.state * 2685821657736338717
+}
+
+// maps colored piece to 0-11 range. 0-5 black pieces, 6-11 white pieces
+func zobristPieceIdx(p piece) int {
+	idx := bits.TrailingZeros8(byte(p & ColorlessPiece))
+	if p&WhitePieceBit != 0 {
+		idx += 6
+	}
+	return idx
+}
+
+func zobristPieceKey(p piece, sq square) uint64 {
+	return zobristPieces[zobristPieceIdx(p)][sq]
+}
+
+func zobristCastlingKey(flags byte) uint64 {
+	return zobristCastling[(flags&castlingFlagsMask)>>1]
+}
+
+func zobristEnPassantKey(enPassSquare square) uint64 {
+	if enPassSquare == InvalidSquare {
+		return 0
+	}
+	return zobristEnPassant[enPassSquare.getFile()]
+}
+
+// Calculates zobrist hash of pos from scratch. In search the hash is updated incrementally in MakeMove().
+func (pos *Position) computeHash() uint64 {
+	var hash uint64
+	for i, p := range pos.board {
+		if p != NullPiece && square(i)&InvalidSquare == 0 {
+			hash ^= zobristPieceKey(p, square(i))
+		}
+	}
+	hash ^= zobristCastlingKey(pos.flags)
+	hash ^= zobristEnPassantKey(pos.enPassSquare)
+	if pos.flags&FlagWhiteTurn == 0 {
+		hash ^= zobristBlackTurn
+	}
+	return hash
+}
+
+// Panics when incrementally updated hash differs from the one calculated from scratch.
+func (pos *Position) AssertHashConsistency(prefix string) {
+	if expected := pos.computeHash(); pos.hash != expected {
+		panic(fmt.Sprintf("%v Incremental hash %016x differs from computed hash %016x in position: %v",
+			prefix, pos.hash, expected, pos))
+	}
+}
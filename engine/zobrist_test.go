@@ -0,0 +1,75 @@
+package engine
+
+import "testing"
+
+// depth of the walk over the perft suite. Enough to cover castling, promotions, e.p. and castling rights loss
+const zobristTestDepth = 3
+
+func TestIncrementalHashMatchesComputedHash(t *testing.T) {
+	forEachPerftPosition(t, func(t *testing.T, fenStr string, gen *Generator) {
+		walkAndCompareHashes(t, gen, zobristTestDepth, "")
+	})
+}
+
+func walkAndCompareHashes(t *testing.T, gen *Generator, depth int, line string) {
+	pos := gen.getTopPos()
+	if expected := pos.computeHash(); pos.hash != expected {
+		t.Fatalf("after moves [%v] incremental hash %016x differs from computed %016x in position: %v",
+			line, pos.hash, expected, pos)
+	}
+	if depth == 0 {
+		return
+	}
+	for _, move := range gen.GenerateMoves() {
+		gen.PushMove(move.mov)
+		walkAndCompareHashes(t, gen, depth-1, line+" "+move.mov.String())
+		gen.PopMove()
+	}
+}
+
+func TestHashIsTheSameForTranspositions(t *testing.T) {
+	first := NewGenerator()
+	for _, uciMove := range []string{"g1f3", "g8f6", "b1c3", "b8c6"} {
+		move, _ := parseMoveString(uciMove)
+		first.ApplyUciMove(move)
+	}
+	second := NewGenerator()
+	for _, uciMove := range []string{"b1c3", "b8c6", "g1f3", "g8f6"} {
+		move, _ := parseMoveString(uciMove)
+		second.ApplyUciMove(move)
+	}
+	if first.getTopPos().hash != second.getTopPos().hash {
+		t.Fatalf("transposed positions have different hashes: %016x vs %016x",
+			first.getTopPos().hash, second.getTopPos().hash)
+	}
+
+	fromFen, err := NewPositionFromFen("r1bqkb1r/pppppppp/2n2n2/8/8/2N2N2/PPPPPPPP/R1BQKB1R w KQkq - 4 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromFen.hash != first.getTopPos().hash {
+		t.Fatalf("position parsed from FEN has different hash: %016x vs %016x",
+			fromFen.hash, first.getTopPos().hash)
+	}
+}
+
+func TestNullMoveHash(t *testing.T) {
+	// en passant square set - null move has to clear it
+	gen, err := NewGeneratorFromFen("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen.PushNullMove()
+	pos := gen.getTopPos()
+	if pos.hash != pos.computeHash() {
+		t.Fatalf("incremental hash %016x differs from computed %016x after null move", pos.hash, pos.computeHash())
+	}
+	expectedFen := "rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 4"
+	if fen := pos.Fen(); fen != expectedFen {
+		t.Fatalf("expected %v but was %v", expectedFen, fen)
+	}
+	gen.PopMove()
+	if gen.getTopPos().enPassSquare != E3 {
+		t.Fatalf("null move changed position below it")
+	}
+}
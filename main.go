@@ -13,7 +13,7 @@ import (
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 
 func main() {
-	printWelcome()
+	magog := engine.NewEngine(os.Stdout)
 	// ---------- runtime profiling stuff ---------------
 	flag.Parse()
 	if *cpuprofile != "" {
@@ -24,14 +24,23 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		engine.ProfileFile = f
+		magog.SetProfileFile(f)
 	}
 	// ---------- runtime profiling stuff -end- ---------
 
 	scanner := bufio.NewScanner(os.Stdin)
-	for !engine.Quit {
-		scanner.Scan()
-		engine.ParseInputLine(scanner.Text())
+	for firstLine := true; !magog.HasQuit(); firstLine = false {
+		if !scanner.Scan() {
+			// stdin closed - nobody will read bestmove of the search if any
+			magog.ParseInputLine("quit")
+			break
+		}
+		inputLine := scanner.Text()
+		// banner is printed once the protocol is known - xboard GUIs take lines they don't expect for errors
+		if firstLine && strings.TrimSpace(inputLine) != "xboard" {
+			printWelcome()
+		}
+		magog.ParseInputLine(inputLine)
 	}
 }
 